@@ -1,22 +1,39 @@
 package main
 
 import (
+	"context"
 	"flag"
-	snapclientset "github.com/kubernetes-csi/external-snapshotter/v2/pkg/client/clientset/versioned"
-	snapinformers "github.com/kubernetes-csi/external-snapshotter/v2/pkg/client/informers/externalversions"
+	"github.com/kubernetes-csi/csi-lib-utils/leaderelection"
 	"github.com/kubesphere/storage-capability/pkg/controller"
 	crdclientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
 	crdinformers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog"
+	"net/http"
 	"time"
 )
 
 var (
 	masterURL  string
 	kubeconfig string
+
+	enableLeaderElection    bool
+	leaderElectionNamespace string
+
+	metricsAddress string
+	metricsPath    string
+)
+
+const (
+	leaseName = "storage-capability-controller"
+	// snapshotDiscoveryInterval is the interval to check whether the snapshot CRDs have been installed.
+	snapshotDiscoveryInterval = time.Minute
 )
 
 func main() {
@@ -45,20 +62,37 @@ func main() {
 	}
 	klog.Info("list: ", list.String())
 
-	snapClient, err := snapclientset.NewForConfig(cfg)
+	// VolumeSnapshotClasses are watched in the most preferred version served by the API server.
+	snapVersion, err := snapshot.DiscoverVersion(kubeClient.Discovery())
 	if err != nil {
-		klog.Fatalf("Error building snapshot clientset: %s", err.Error())
+		klog.Fatalf("Error discovering %s: %s", snapshot.GroupName, err.Error())
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building dynamic client: %s", err.Error())
 	}
 
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
 	crdInformerFactory := crdinformers.NewSharedInformerFactory(crdClient, time.Second*30)
-	snapInformerFactory := snapinformers.NewSharedInformerFactory(snapClient, time.Second*30)
+	snapInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, time.Second*30)
+
+	var snapInformer snapshot.ClassInformer
+	if snapVersion != "" {
+		klog.Infof("Using %s/%s", snapshot.GroupName, snapVersion)
+		snapInformer = snapshot.NewClassInformer(snapInformerFactory, snapVersion)
+	} else {
+		klog.Warningf("None of the versions %v of %s is served, snapshot features are unavailable until the snapshot CRDs are installed",
+			snapshot.SupportedVersions, snapshot.GroupName)
+	}
 
 	controller := controller.NewController(kubeClient, crdClient,
 		kubeInformerFactory.Storage().V1().StorageClasses(),
-		snapInformerFactory.Snapshot().V1beta1().VolumeSnapshotClasses(),
+		snapInformer,
 		crdInformerFactory.Storage().V1alpha1().ProvisionerCapabilities(),
 		crdInformerFactory.Storage().V1alpha1().StorageClassCapabilities(),
+		crdInformerFactory.Storage().V1alpha1().NodeCapabilities(),
+		crdInformerFactory.Storage().V1alpha1().CapabilityOverrides(),
+		kubeInformerFactory.Core().V1().Nodes(),
 	)
 
 	// notice that there is no need to run Start methods in a separate goroutine. (i.e. go kubeInformerFactory.Start(stopCh)
@@ -66,13 +100,49 @@ func main() {
 	kubeInformerFactory.Start(stopCh)
 	crdInformerFactory.Start(stopCh)
 	snapInformerFactory.Start(stopCh)
+	if snapVersion == "" {
+		go func() {
+			version, err := snapshot.WaitForVersion(kubeClient.Discovery(), snapshotDiscoveryInterval, stopCh)
+			if err != nil {
+				return
+			}
+			klog.Infof("Using %s/%s", snapshot.GroupName, version)
+			controller.WatchSnapshotClasses(snapshot.NewClassInformer(snapInformerFactory, version), stopCh)
+			snapInformerFactory.Start(stopCh)
+		}()
+	}
+
+	if metricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle(metricsPath, legacyregistry.Handler())
+		go func() {
+			klog.Fatal(http.ListenAndServe(metricsAddress, mux))
+		}()
+	}
 
-	if err = controller.Start(stopCh); err != nil {
-		klog.Fatalf("Error running controller: %s", err.Error())
+	run := func(ctx context.Context) {
+		if err := controller.Start(stopCh); err != nil {
+			klog.Fatalf("Error running controller: %s", err.Error())
+		}
+	}
+	if !enableLeaderElection {
+		run(context.TODO())
+		return
+	}
+	le := leaderelection.NewLeaderElection(kubeClient, leaseName, run)
+	if leaderElectionNamespace != "" {
+		le.WithNamespace(leaderElectionNamespace)
+	}
+	if err := le.Run(); err != nil {
+		klog.Fatalf("Error initializing leader election: %s", err.Error())
 	}
 }
 
 func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	flag.BoolVar(&enableLeaderElection, "leader-election", false, "Enable leader election, so only one replica of the controller syncs StorageClassCapabilities.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "Namespace of the leader election Lease. Defaults to the namespace of the pod.")
+	flag.StringVar(&metricsAddress, "metrics-address", "", "The TCP network address where the prometheus metrics endpoint will listen, e.g. :8080. The endpoint is disabled if empty.")
+	flag.StringVar(&metricsPath, "metrics-path", "/metrics", "The HTTP path where prometheus metrics will be exposed.")
 }
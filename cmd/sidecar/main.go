@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"github.com/kubernetes-csi/csi-lib-utils/connection"
 	"github.com/kubernetes-csi/csi-lib-utils/metrics"
+	"github.com/kubernetes-csi/csi-lib-utils/rpc"
 	clientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
 	"github.com/kubesphere/storage-capability/pkg/sidecar"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -26,6 +30,17 @@ var (
 	csiNodeAddress = flag.String("csi-node-address", "", "Address of the CSI Node driver socket.")
 	timeout        = flag.Duration("timeout", defaultTimeout, "The timeout for any RPCs to the CSI driver. Default is 1 minute.")
 	resyncPeriod   = flag.Duration("resync-period", 60*time.Second, "Resync interval of the controller.")
+	mode           = flag.String("mode", sidecar.ModeController, "Mode of the sidecar, controller or node. In node mode the sidecar probes the CSI node plugin at --csi-node-address and records a NodeCapability.")
+	nodeName       = flag.String("node-name", os.Getenv("NODE_NAME"), "Name of the node the sidecar runs on. Required in node mode.")
+	volumeModes    = flag.String("volume-modes", "", "Supported volume modes and access modes, e.g. \"Filesystem=ReadWriteOnce,ReadWriteMany;Block=ReadWriteOnce\". Overrides --probe-volume-id.")
+	probeVolumeID  = flag.String("probe-volume-id", "", "ID of an existing volume used to probe supported volume modes and access modes by ValidateVolumeCapabilities.")
+	cleanupOnExit  = flag.Bool("cleanup-on-exit", false, "Delete the NodeCapability of the node when the sidecar exits in node mode. Only enable it to uninstall the node plugin, otherwise every rollout or restart drops the node from the topology until the next probe.")
+
+	enableLeaderElection    = flag.Bool("leader-election", false, "Enable leader election in controller mode. The Lease is named after the CSI driver, so only one replica per driver updates the ProvisionerCapability.")
+	leaderElectionNamespace = flag.String("leader-election-namespace", "", "Namespace of the leader election Lease. Defaults to the namespace of the pod.")
+
+	metricsAddress = flag.String("metrics-address", "", "The TCP network address where the prometheus metrics endpoint will listen, e.g. :8080. The endpoint is disabled if empty.")
+	metricsPath    = flag.String("metrics-path", "/metrics", "The HTTP path where prometheus metrics will be exposed.")
 )
 
 func main() {
@@ -49,26 +64,87 @@ func main() {
 		klog.Error(err.Error())
 		os.Exit(1)
 	}
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes clientset: %s", err.Error())
+	}
 	// Create CSI gRPC client connection
+	address := *csiAddress
+	if *mode == sidecar.ModeNode {
+		address = *csiNodeAddress
+	}
 	metricsManager := metrics.NewCSIMetricsManager("" /* driverName */)
-	csiConn, err := connection.Connect(*csiAddress, metricsManager, connection.OnConnectionLoss(connection.ExitOnConnectionLoss()))
+	csiConn, err := connection.Connect(address, metricsManager, connection.OnConnectionLoss(connection.ExitOnConnectionLoss()))
 	if err != nil {
 		klog.Errorf("error connecting to CSI driver: %v", err)
 		os.Exit(1)
 	}
-	controller := sidecar.NewCSISidecarController(
-		clientset,
-		csiConn,
-		*timeout,
-		*resyncPeriod,
-	)
+	// Stop on SIGINT or SIGTERM, then let the controller clean up
 	stopCh := make(chan struct{})
-	go controller.Run(stopCh)
-	// ...until SIGINT
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
-	close(stopCh)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		close(stopCh)
+	}()
+	getDriverName := func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		return rpc.GetDriverName(ctx, csiConn)
+	}
+	var driverName string
+	if *enableLeaderElection && *mode == sidecar.ModeController {
+		// Leader election needs the name for the Lease, wait for a slow starting driver.
+		driverName, err = sidecar.WaitForDriverName(getDriverName, time.Second, time.Minute, stopCh)
+		if err != nil {
+			klog.Fatalf("Error getting CSI driver name: %s", err)
+		}
+	} else if driverName, err = getDriverName(); err != nil {
+		// RPC metrics are recorded for an unknown driver, and probe failures are only recorded in
+		// ProvisionerCapability after the first successful probe.
+		klog.Errorf("Error getting CSI driver name: %s", err)
+	}
+	metricsManager.SetDriverName(driverName)
+	sidecar.RegisterMetrics(metricsManager.GetRegistry())
+	metricsManager.StartMetricsEndpoint(*metricsAddress, *metricsPath)
+	var controller sidecar.Runner
+	switch *mode {
+	case sidecar.ModeController:
+		modes, err := sidecar.ParseVolumeModes(*volumeModes)
+		if err != nil {
+			klog.Fatalf("Invalid --volume-modes: %s", err)
+		}
+		controller = sidecar.NewCSISidecarController(
+			clientset,
+			csiConn,
+			driverName,
+			*timeout,
+			*resyncPeriod,
+			modes,
+			*probeVolumeID,
+		)
+	case sidecar.ModeNode:
+		if *nodeName == "" {
+			klog.Fatal("--node-name or NODE_NAME environment variable is required in node mode")
+		}
+		controller = sidecar.NewCSINodeSidecarController(
+			clientset,
+			kubeClient,
+			csiConn,
+			*nodeName,
+			*timeout,
+			*resyncPeriod,
+			*cleanupOnExit,
+		)
+	default:
+		klog.Fatalf("Unknown mode %q, must be %s or %s", *mode, sidecar.ModeController, sidecar.ModeNode)
+	}
+	runner := sidecar.NewRunner(controller, *mode, driverName, sidecar.LeaderElectionConfig{
+		Enabled:    *enableLeaderElection,
+		KubeClient: kubeClient,
+		Namespace:  *leaderElectionNamespace,
+	})
+	runner.Run(stopCh)
 }
 
 func init() {
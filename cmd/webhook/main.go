@@ -1,14 +1,25 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
+	crdclientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
+	crdinformers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions"
 	"github.com/kubesphere/storage-capability/pkg/webhook"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/component-base/metrics/legacyregistry"
 
 	"k8s.io/klog"
 	"net/http"
+	"os"
 	"path/filepath"
+	"time"
 )
 
 const (
@@ -20,6 +31,20 @@ const (
 var (
 	masterURL  string
 	kubeconfig string
+
+	metricsAddress string
+	metricsPath    string
+
+	sidecarConfigNamespace string
+	sidecarConfigName      string
+
+	pvcValidation      bool
+	snapshotValidation bool
+
+	selfManagedCerts bool
+	certSecretName   string
+	certValidity     time.Duration
+	serviceName      string
 )
 
 func main() {
@@ -39,19 +64,121 @@ func main() {
 	if err != nil {
 		klog.Fatalf("Error building kubernetes clientset: %s", err.Error())
 	}
+	// Metrics are served over plain HTTP on a separate port, so Prometheus does not need the webhook CA.
+	if metricsAddress != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle(metricsPath, legacyregistry.Handler())
+		go func() {
+			klog.Fatal(http.ListenAndServe(metricsAddress, metricsMux))
+		}()
+	}
+	// Sidecar injection config is hot-reloaded from the ConfigMap.
+	sidecarConfig := webhook.NewSidecarInjectionConfigStore()
+	if sidecarConfigNamespace == "" {
+		sidecarConfigNamespace = os.Getenv("POD_NAMESPACE")
+	}
+	if sidecarConfigNamespace == "" {
+		klog.Warning("Namespace of the sidecar injection config is unknown, use the default config")
+	} else if err := sidecarConfig.Watch(kubeClient, sidecarConfigNamespace, sidecarConfigName, wait.NeverStop); err != nil {
+		klog.Fatalf("Error watching sidecar injection config: %s", err.Error())
+	}
+	// RBAC of injected sidecars is created asynchronously, out of the admission path, and deleted when unused.
+	podInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute,
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = webhook.LabelInjected + "=true"
+		}))
+	bindingInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute,
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = webhook.LabelOwner + "=" + webhook.OwnerStorageCapability
+		}))
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	// Namespaces are looked up from the cache, so injection does not depend on the API server in the admission path.
+	injector := webhook.NewSidecarInjector(sidecarConfig, kubeInformerFactory.Core().V1().Namespaces())
+	scInformer := kubeInformerFactory.Storage().V1().StorageClasses()
+	capabilityValidator := webhook.NewCapabilityValidator(scInformer.Lister())
+	rbacReconciler := webhook.NewRBACReconciler(kubeClient, podInformerFactory.Core().V1().Pods(),
+		kubeInformerFactory.Core().V1().ServiceAccounts(), bindingInformerFactory.Rbac().V1().ClusterRoleBindings())
+	podInformerFactory.Start(wait.NeverStop)
+	bindingInformerFactory.Start(wait.NeverStop)
+	kubeInformerFactory.Start(wait.NeverStop)
+	if ok := cache.WaitForCacheSync(wait.NeverStop, kubeInformerFactory.Core().V1().Namespaces().Informer().HasSynced,
+		scInformer.Informer().HasSynced); !ok {
+		klog.Fatal("Failed to wait for Namespace and StorageClass caches to sync")
+	}
+	go func() {
+		if err := rbacReconciler.Run(1, wait.NeverStop); err != nil {
+			klog.Fatalf("Error running sidecar RBAC reconciler: %s", err.Error())
+		}
+	}()
 	// Admission Webhook Server
 	mux := http.NewServeMux()
-	mux.Handle("/mutate", webhook.AdmitFuncHandler(webhook.AddSidecarContainer, kubeClient))
+	mux.Handle("/mutate", webhook.AdmitFuncHandler(injector.AddSidecarContainer, kubeClient))
+	mux.Handle("/validate", webhook.ValidateFuncHandler(capabilityValidator.Validate, kubeClient))
+	mux.Handle("/convert", webhook.ConvertHandler())
+	if pvcValidation || snapshotValidation {
+		crdClient, err := crdclientset.NewForConfig(cfg)
+		if err != nil {
+			klog.Fatalf("Error building crd clientset: %s", err.Error())
+		}
+		crdInformerFactory := crdinformers.NewSharedInformerFactory(crdClient, time.Second*30)
+		sccapInformer := crdInformerFactory.Storage().V1alpha1().StorageClassCapabilities()
+		// The informer must be registered before the factory is started.
+		sccapInformer.Informer()
+		crdInformerFactory.Start(wait.NeverStop)
+		if ok := cache.WaitForCacheSync(wait.NeverStop, sccapInformer.Informer().HasSynced); !ok {
+			klog.Fatal("Failed to wait for StorageClassCapability cache to sync")
+		}
+		if pvcValidation {
+			pvcValidator := webhook.NewPVCValidator(sccapInformer.Lister(), kubeClient)
+			mux.Handle("/validate-pvc", webhook.ValidateFuncHandler(pvcValidator.Validate, kubeClient))
+		}
+		if snapshotValidation {
+			dynamicClient, err := dynamic.NewForConfig(cfg)
+			if err != nil {
+				klog.Fatalf("Error building dynamic client: %s", err.Error())
+			}
+			snapshotValidator := webhook.NewSnapshotValidator(sccapInformer.Lister(), dynamicClient)
+			mux.Handle("/validate-snapshot", webhook.ValidateFuncHandler(snapshotValidator.Validate, kubeClient))
+		}
+	}
 	server := &http.Server{
 		// We listen on port 8443 such that we do not need root privileges or extra capabilities for this server.
 		// The Service object will take care of mapping this port to the HTTPS port 443.
 		Addr:    ":8443",
 		Handler: mux,
 	}
-	klog.Fatal(server.ListenAndServeTLS(certPath, keyPath))
+	if !selfManagedCerts {
+		klog.Fatal(server.ListenAndServeTLS(certPath, keyPath))
+	}
+	// The certificate is generated into a Secret and reloaded on renewal, its CA is patched into the webhooks.
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		klog.Fatal("POD_NAMESPACE must be set to manage certificates")
+	}
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building dynamic client: %s", err.Error())
+	}
+	certManager := webhook.NewCertManager(kubeClient, dynamicClient, namespace, certSecretName, serviceName, certValidity)
+	if err := certManager.Run(wait.NeverStop); err != nil {
+		klog.Fatalf("Error managing certificate: %s", err.Error())
+	}
+	server.TLSConfig = &tls.Config{GetCertificate: certManager.GetCertificate}
+	klog.Fatal(server.ListenAndServeTLS("", ""))
 }
 
 func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	flag.StringVar(&metricsAddress, "metrics-address", "", "The TCP network address where the prometheus metrics endpoint will listen, e.g. :8080. The endpoint is disabled if empty.")
+	flag.StringVar(&metricsPath, "metrics-path", "/metrics", "The HTTP path where prometheus metrics will be exposed.")
+	flag.StringVar(&sidecarConfigNamespace, "sidecar-config-namespace", "", "Namespace of the ConfigMap of the sidecar injection config. Defaults to the POD_NAMESPACE environment variable.")
+	flag.StringVar(&sidecarConfigName, "sidecar-config-name", "storage-capability-sidecar-injection", "Name of the ConfigMap of the sidecar injection config.")
+	flag.BoolVar(&pvcValidation, "pvc-validation", false, "Serve /validate-pvc, which denies PersistentVolumeClaims using features not supported by the StorageClass. "+
+		"Warnings about offline expansion are recorded as events of the claim and in the audit log, they are not shown by kubectl because AdmissionResponse has no warnings field in k8s.io/api v0.17.")
+	flag.BoolVar(&snapshotValidation, "snapshot-validation", false, "Serve /validate-snapshot, which denies VolumeSnapshots of claims whose StorageClass does not support snapshot.")
+	flag.BoolVar(&selfManagedCerts, "self-managed-certs", false, "Generate the serving certificate into a Secret of the POD_NAMESPACE, renew it before expiry and patch its CA into the webhooks calling the Service, instead of reading it from "+tlsDir+".")
+	flag.StringVar(&certSecretName, "cert-secret-name", "webhook-server-tls", "Name of the Secret of the self-managed certificate.")
+	flag.DurationVar(&certValidity, "cert-validity", webhook.DefaultCertValidity, "Validity of the self-managed certificate, it is renewed once less than a third is left.")
+	flag.StringVar(&serviceName, "service-name", "webhook-server", "Name of the Service of the webhook in the POD_NAMESPACE, the self-managed certificate is issued for.")
 }
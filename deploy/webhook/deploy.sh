@@ -23,25 +23,47 @@ set -euo pipefail
 basedir="$(dirname "$0")"
 keydir="$(mktemp -d)"
 
-# Generate keys into a temporary directory.
-echo "Generating TLS keys ..."
-"${basedir}/generate-keys.sh" "$keydir"
+# With SELF_MANAGED_CERTS=true, the webhook generates and renews its certificate and patches the caBundles itself.
+self_managed_certs="${SELF_MANAGED_CERTS:-false}"
 
 # Create the `webhook-demo` namespace. This cannot be part of the YAML file as we first need to create the TLS secret,
 # which would fail otherwise.
 echo "Creating Kubernetes objects ..."
 kubectl create namespace webhook-demo
 
-# Create the TLS secret for the generated keys.
-kubectl -n webhook-demo create secret tls webhook-server-tls \
-    --cert "${keydir}/webhook-server-tls.crt" \
-    --key "${keydir}/webhook-server-tls.key"
+ca_pem_b64=""
+if [ "$self_managed_certs" != "true" ]; then
+    # Generate keys into a temporary directory.
+    echo "Generating TLS keys ..."
+    "${basedir}/generate-keys.sh" "$keydir"
 
-# Read the PEM-encoded CA certificate, base64 encode it, and replace the `${CA_PEM_B64}` placeholder in the YAML
-# template with it. Then, create the Kubernetes resources.
-ca_pem_b64="$(openssl base64 -A <"${keydir}/ca.crt")"
-sed -e 's@${CA_PEM_B64}@'"$ca_pem_b64"'@g' <"${basedir}/webhook.yaml.template" \
-    | kubectl create -f -
+    # Create the TLS secret for the generated keys.
+    kubectl -n webhook-demo create secret tls webhook-server-tls \
+        --cert "${keydir}/webhook-server-tls.crt" \
+        --key "${keydir}/webhook-server-tls.key"
+
+    # Read the PEM-encoded CA certificate and base64 encode it.
+    ca_pem_b64="$(openssl base64 -A <"${keydir}/ca.crt")"
+fi
+
+# Replace the `${CA_PEM_B64}` and `${SELF_MANAGED_CERTS}` placeholders in the YAML template. Then, create the
+# Kubernetes resources.
+sed -e 's@${CA_PEM_B64}@'"$ca_pem_b64"'@g' -e 's@${SELF_MANAGED_CERTS}@'"$self_managed_certs"'@g' \
+    <"${basedir}/webhook.yaml.template" | kubectl create -f -
+
+# The PVC validation is optional.
+if [ "${ENABLE_PVC_VALIDATION:-false}" = "true" ]; then
+    sed -e 's@${CA_PEM_B64}@'"$ca_pem_b64"'@g' <"${basedir}/pvc-validation.yaml.template" \
+        | kubectl create -f -
+fi
+
+# Patch the CA certificate into the conversion webhook of storage capability CRDs.
+if [ "$self_managed_certs" != "true" ]; then
+    for crd in provisionercapabilities.storage.kubesphere.io storageclasscapabilities.storage.kubesphere.io; do
+        kubectl patch crd "$crd" --type=merge \
+            -p '{"spec":{"conversion":{"webhook":{"clientConfig":{"caBundle":"'"$ca_pem_b64"'"}}}}}'
+    done
+fi
 
 # Delete the key directory to prevent abuse (DO NOT USE THESE KEYS ANYWHERE ELSE).
 rm -rf "$keydir"
@@ -1,5 +1,9 @@
 # !/bin/bash
 kubectl delete ns webhook-demo
 kubectl delete MutatingWebhookConfiguration demo-webhook
+kubectl delete ValidatingWebhookConfiguration storage-capability-validation
+kubectl delete ValidatingWebhookConfiguration storage-capability-snapshot-validation
+kubectl delete ValidatingWebhookConfiguration storage-capability-pvc-validation --ignore-not-found
 kubectl delete ClusterRole storage-capability-webhook
-kubectl delete ClusterRoleBinding storage-capability-webhook
\ No newline at end of file
+kubectl delete ClusterRoleBinding storage-capability-webhook
+kubectl delete ClusterRoleBinding -l owner=storage-capability
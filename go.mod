@@ -3,30 +3,19 @@ module github.com/kubesphere/storage-capability
 go 1.12
 
 require (
-	github.com/container-storage-interface/spec v1.2.0
-	github.com/evanphx/json-patch v4.5.0+incompatible // indirect
-	github.com/golang/groupcache v0.0.0-20190129154638-5b532d6fd5ef // indirect
+	github.com/container-storage-interface/spec v1.5.0
 	github.com/google/go-cmp v0.3.1 // indirect
-	github.com/googleapis/gnostic v0.2.0 // indirect
+	github.com/google/gofuzz v1.1.0
 	github.com/imdario/mergo v0.3.7 // indirect
 	github.com/kubernetes-csi/csi-lib-utils v0.7.0
-	github.com/kubernetes-csi/csi-test v2.0.0+incompatible // indirect
-	github.com/kubernetes-csi/external-snapshotter/v2 v2.1.1
 	github.com/pkg/errors v0.8.1
-	github.com/prometheus/client_golang v1.0.0 // indirect
 	golang.org/x/crypto v0.0.0-20200220183623-bac4c82f6975 // indirect
-	golang.org/x/net v0.0.0-20191209160850-c0dbc17a3553 // indirect
-	golang.org/x/sys v0.0.0-20191220220014-0732a990476f // indirect
-	gonum.org/v1/netlib v0.0.0-20190331212654-76723241ea4e // indirect
-	google.golang.org/genproto v0.0.0-20191220175831-5c49e3ecc1c1 // indirect
 	google.golang.org/grpc v1.29.1
 	k8s.io/api v0.17.2
 	k8s.io/apimachinery v0.17.6-beta.0.0.20200429001804-891b87d7c4bb
 	k8s.io/client-go v0.17.0
 	k8s.io/code-generator v0.17.6-beta.0.0.20200429001238-c05f3fad9056
+	k8s.io/component-base v0.17.0
 	k8s.io/klog v1.0.0
-	k8s.io/kube-openapi v0.0.0-20200410145947-bcb3869e6f29 // indirect
-	k8s.io/kubernetes v1.14.0 // indirect
 	k8s.io/utils v0.0.0-20200324210504-a9aa75ae1b89 // indirect
-	sigs.k8s.io/structured-merge-diff v0.0.0-20190525122527-15d366b2352e // indirect
 )
@@ -0,0 +1,74 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FindCondition returns the condition with the given type, or nil if it is not present.
+func (in *CapabilityStatus) FindCondition(conditionType CapabilityConditionType) *CapabilityCondition {
+	for i := range in.Conditions {
+		if in.Conditions[i].Type == conditionType {
+			return &in.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsConditionTrue reports whether the condition with the given type is present and True.
+func (in *CapabilityStatus) IsConditionTrue(conditionType CapabilityConditionType) bool {
+	cond := in.FindCondition(conditionType)
+	return cond != nil && cond.Status == metav1.ConditionTrue
+}
+
+// SetCondition adds or updates the condition. LastTransitionTime is only changed
+// when the status of the condition changes.
+func (in *CapabilityStatus) SetCondition(newCondition CapabilityCondition) {
+	existing := in.FindCondition(newCondition.Type)
+	if existing == nil {
+		if newCondition.LastTransitionTime.IsZero() {
+			newCondition.LastTransitionTime = metav1.Now()
+		}
+		in.Conditions = append(in.Conditions, newCondition)
+		return
+	}
+	if existing.Status != newCondition.Status {
+		existing.Status = newCondition.Status
+		if newCondition.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		} else {
+			existing.LastTransitionTime = newCondition.LastTransitionTime
+		}
+	}
+	existing.ObservedGeneration = newCondition.ObservedGeneration
+	existing.Reason = newCondition.Reason
+	existing.Message = newCondition.Message
+}
+
+// RemoveCondition removes the condition with the given type.
+func (in *CapabilityStatus) RemoveCondition(conditionType CapabilityConditionType) {
+	var conditions []CapabilityCondition
+	for _, c := range in.Conditions {
+		if c.Type != conditionType {
+			conditions = append(conditions, c)
+		}
+	}
+	in.Conditions = conditions
+}
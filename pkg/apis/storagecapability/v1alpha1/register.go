@@ -51,6 +51,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&StorageClassCapabilityList{},
 		&ProvisionerCapability{},
 		&ProvisionerCapabilityList{},
+		&NodeCapability{},
+		&NodeCapabilityList{},
+		&CapabilityOverride{},
+		&CapabilityOverrideList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
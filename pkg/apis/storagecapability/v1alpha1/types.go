@@ -19,18 +19,19 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
 // +genclient:nonNamespaced
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type ProvisionerCapability struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ProvisionerCapabilitySpec `json:"spec"`
+	Spec   ProvisionerCapabilitySpec `json:"spec"`
+	Status CapabilityStatus          `json:"status,omitempty"`
 }
 
 type ProvisionerCapabilitySpec struct {
@@ -47,6 +48,7 @@ type ProvisionerCapabilitySpecFeatures struct {
 	Topology bool                                      `json:"topology"`
 	Volume   ProvisionerCapabilitySpecFeaturesVolume   `json:"volume"`
 	Snapshot ProvisionerCapabilitySpecFeaturesSnapshot `json:"snapshot"`
+	Node     ProvisionerCapabilitySpecFeaturesNode     `json:"node"`
 }
 
 type ProvisionerCapabilitySpecFeaturesVolume struct {
@@ -56,6 +58,23 @@ type ProvisionerCapabilitySpecFeaturesVolume struct {
 	Clone  bool       `json:"clone"`
 	Stats  bool       `json:"stats"`
 	Expand ExpandMode `json:"expandMode"`
+	// The fields below are determined by ControllerGetCapabilities in ControllerServer.
+	Capacity              bool `json:"capacity"`
+	ReadOnlyAttach        bool `json:"readOnlyAttach"`
+	ControllerExpand      bool `json:"controllerExpand"`
+	ListPublishedNodes    bool `json:"listPublishedNodes"`
+	Condition             bool `json:"condition"`
+	Get                   bool `json:"get"`
+	SingleNodeMultiWriter bool `json:"singleNodeMultiWriter"`
+	// VolumeModes lists the access modes supported in each volume mode. It is determined by
+	// ValidateVolumeCapabilities in ControllerServer against a probe volume, or supplied by the operator.
+	VolumeModes []VolumeModeCapability `json:"volumeModes,omitempty"`
+}
+
+// VolumeModeCapability lists the access modes a driver supports for volumes in the volume mode.
+type VolumeModeCapability struct {
+	VolumeMode  corev1.PersistentVolumeMode         `json:"volumeMode"`
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
 }
 
 type ProvisionerCapabilitySpecFeaturesSnapshot struct {
@@ -63,6 +82,14 @@ type ProvisionerCapabilitySpecFeaturesSnapshot struct {
 	List   bool `json:"list"`
 }
 
+// ProvisionerCapabilitySpecFeaturesNode is determined by NodeGetCapabilities in NodeServer.
+type ProvisionerCapabilitySpecFeaturesNode struct {
+	StageUnstage          bool `json:"stageUnstage"`
+	Expand                bool `json:"expand"`
+	Condition             bool `json:"condition"`
+	SingleNodeMultiWriter bool `json:"singleNodeMultiWriter"`
+}
+
 type ExpandMode string
 
 const (
@@ -71,6 +98,65 @@ const (
 	ExpandModeOnline  ExpandMode = "ONLINE"
 )
 
+// CapabilityStatus is shared by ProvisionerCapability and StorageClassCapability.
+// It tells whether the capability data is fresh, stale or failed to probe.
+type CapabilityStatus struct {
+	// ObservedGeneration is the most recent generation observed by the writer of this status.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastProbeTime is the last time the CSI plugin was successfully probed.
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	// Conditions represent the latest available observations of the capability object.
+	Conditions []CapabilityCondition `json:"conditions,omitempty"`
+	// Topology is aggregated from NodeCapability of all nodes. For StorageClassCapability
+	// it is further restricted by the AllowedTopologies of StorageClass.
+	Topology *TopologyStatus `json:"topology,omitempty"`
+	// UnsupportedFeatures lists the features of the provisioner disabled in StorageClassCapability because
+	// the Kubernetes cluster does not support them. It is only set on StorageClassCapability.
+	UnsupportedFeatures []UnsupportedFeature `json:"unsupportedFeatures,omitempty"`
+}
+
+// UnsupportedFeature tells why a feature is disabled, e.g. the server version is too old or an API is not served.
+type UnsupportedFeature struct {
+	// Feature is the path of the feature in spec.features, e.g. volume.expandMode.
+	Feature string `json:"feature"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// TopologyStatus lists the topology keys and segments reported by the node plugins of a driver.
+type TopologyStatus struct {
+	// Keys are the topology keys reported by any node, sorted.
+	Keys []string `json:"keys,omitempty"`
+	// Segments are the distinct accessible topology segments, e.g. {"topology.example.io/zone": "zone-a"}.
+	Segments []map[string]string `json:"segments,omitempty"`
+}
+
+type CapabilityConditionType string
+
+const (
+	// CapabilityReady means the capability data is up to date.
+	CapabilityReady CapabilityConditionType = "Ready"
+	// CapabilityProbeFailed means the last probe of the CSI plugin failed.
+	CapabilityProbeFailed CapabilityConditionType = "ProbeFailed"
+	// CapabilityStale means the capability data has not been refreshed for a while.
+	CapabilityStale CapabilityConditionType = "Stale"
+	// CapabilityProvisionerMissing means no ProvisionerCapability exists for the StorageClass provisioner.
+	CapabilityProvisionerMissing CapabilityConditionType = "ProvisionerMissing"
+	// CapabilitySnapshotUnavailable means snapshot features of the StorageClass are not available, e.g. the
+	// snapshot API is not served or no VolumeSnapshotClass matches. The reason tells which.
+	CapabilitySnapshotUnavailable CapabilityConditionType = "SnapshotUnavailable"
+)
+
+// CapabilityCondition follows the layout of the standard Kubernetes condition.
+type CapabilityCondition struct {
+	Type               CapabilityConditionType `json:"type"`
+	Status             metav1.ConditionStatus  `json:"status"`
+	ObservedGeneration int64                   `json:"observedGeneration,omitempty"`
+	LastTransitionTime metav1.Time             `json:"lastTransitionTime,omitempty"`
+	Reason             string                  `json:"reason,omitempty"`
+	Message            string                  `json:"message,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type ProvisionerCapabilityList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -81,24 +167,31 @@ type ProvisionerCapabilityList struct {
 
 // +genclient
 // +genclient:nonNamespaced
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type StorageClassCapability struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec StorageClassCapabilitySpec `json:"spec"`
+	Spec   StorageClassCapabilitySpec `json:"spec"`
+	Status CapabilityStatus           `json:"status,omitempty"`
 }
 
 type StorageClassCapabilitySpec struct {
 	Provisioner string                             `json:"provisioner"`
 	Features    StorageClassCapabilitySpecFeatures `json:"features"`
+	// SnapshotClassName is the VolumeSnapshotClass chosen for the StorageClass. Snapshot features
+	// are only reported if a VolumeSnapshotClass of the provisioner is found.
+	SnapshotClassName string `json:"snapshotClassName,omitempty"`
+	// SnapshotClassNames lists all VolumeSnapshotClasses of the provisioner by name, SnapshotClassName is the
+	// one chosen among them.
+	SnapshotClassNames []string `json:"snapshotClassNames,omitempty"`
 }
 
 type StorageClassCapabilitySpecFeatures struct {
 	Topology bool                                      `json:"topology"`
 	Volume   ProvisionerCapabilitySpecFeaturesVolume   `json:"volume"`
 	Snapshot ProvisionerCapabilitySpecFeaturesSnapshot `json:"snapshot"`
+	Node     ProvisionerCapabilitySpecFeaturesNode     `json:"node"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -107,3 +200,123 @@ type StorageClassCapabilityList struct {
 	metav1.ListMeta `json:"metadata"`
 	Items           []StorageClassCapability `json:"items"`
 }
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeCapability records the capability of a CSI node plugin on a single node.
+// It is written by the sidecar running in node mode and named <node>.<driver>.
+type NodeCapability struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeCapabilitySpec `json:"spec"`
+	Status CapabilityStatus   `json:"status,omitempty"`
+}
+
+type NodeCapabilitySpec struct {
+	NodeName   string                              `json:"nodeName"`
+	PluginInfo ProvisionerCapabilitySpecPluginInfo `json:"pluginInfo"`
+	// The fields below are determined by NodeGetInfo in NodeServer.
+	NodeID             string            `json:"nodeID"`
+	MaxVolumesPerNode  int64             `json:"maxVolumesPerNode,omitempty"`
+	AccessibleTopology map[string]string `json:"accessibleTopology,omitempty"`
+	// Features is determined by NodeGetCapabilities in NodeServer.
+	Features ProvisionerCapabilitySpecFeaturesNode `json:"features"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type NodeCapabilityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NodeCapability `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CapabilityOverride overrides the features of StorageClassCapability for StorageClasses of a
+// provisioner whose parameters match the rules, e.g. a disk type that does not support snapshot.
+type CapabilityOverride struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CapabilityOverrideSpec `json:"spec"`
+}
+
+type CapabilityOverrideSpec struct {
+	Provisioner string `json:"provisioner"`
+	// Rules are evaluated in order. A later rule takes precedence over an earlier one.
+	Rules []CapabilityOverrideRule `json:"rules"`
+}
+
+type CapabilityOverrideRule struct {
+	// Parameters must all match the parameters of StorageClass. A rule without parameters matches all StorageClasses.
+	Parameters []ParameterMatcher `json:"parameters,omitempty"`
+	Features   FeaturesOverride   `json:"features"`
+}
+
+type ParameterMatchOperator string
+
+const (
+	ParameterMatchIn           ParameterMatchOperator = "In"
+	ParameterMatchNotIn        ParameterMatchOperator = "NotIn"
+	ParameterMatchExists       ParameterMatchOperator = "Exists"
+	ParameterMatchDoesNotExist ParameterMatchOperator = "DoesNotExist"
+)
+
+// ParameterMatcher matches a StorageClass parameter, in the same way as a label selector requirement.
+type ParameterMatcher struct {
+	Key      string                 `json:"key"`
+	Operator ParameterMatchOperator `json:"operator"`
+	Values   []string               `json:"values,omitempty"`
+}
+
+// FeaturesOverride holds the features to override. Nil fields are left unchanged.
+type FeaturesOverride struct {
+	Topology *bool                     `json:"topology,omitempty"`
+	Volume   *VolumeFeaturesOverride   `json:"volume,omitempty"`
+	Snapshot *SnapshotFeaturesOverride `json:"snapshot,omitempty"`
+	Node     *NodeFeaturesOverride     `json:"node,omitempty"`
+}
+
+type VolumeFeaturesOverride struct {
+	Create                *bool                  `json:"create,omitempty"`
+	Attach                *bool                  `json:"attach,omitempty"`
+	List                  *bool                  `json:"list,omitempty"`
+	Clone                 *bool                  `json:"clone,omitempty"`
+	Stats                 *bool                  `json:"stats,omitempty"`
+	Expand                *ExpandMode            `json:"expandMode,omitempty"`
+	Capacity              *bool                  `json:"capacity,omitempty"`
+	ReadOnlyAttach        *bool                  `json:"readOnlyAttach,omitempty"`
+	ControllerExpand      *bool                  `json:"controllerExpand,omitempty"`
+	ListPublishedNodes    *bool                  `json:"listPublishedNodes,omitempty"`
+	Condition             *bool                  `json:"condition,omitempty"`
+	Get                   *bool                  `json:"get,omitempty"`
+	SingleNodeMultiWriter *bool                  `json:"singleNodeMultiWriter,omitempty"`
+	VolumeModes           []VolumeModeCapability `json:"volumeModes,omitempty"`
+}
+
+type SnapshotFeaturesOverride struct {
+	Create *bool `json:"create,omitempty"`
+	List   *bool `json:"list,omitempty"`
+}
+
+type NodeFeaturesOverride struct {
+	StageUnstage          *bool `json:"stageUnstage,omitempty"`
+	Expand                *bool `json:"expand,omitempty"`
+	Condition             *bool `json:"condition,omitempty"`
+	SingleNodeMultiWriter *bool `json:"singleNodeMultiWriter,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CapabilityOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []CapabilityOverride `json:"items"`
+}
@@ -21,15 +21,357 @@ limitations under the License.
 package v1alpha1
 
 import (
+	v1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapabilityCondition) DeepCopyInto(out *CapabilityCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapabilityCondition.
+func (in *CapabilityCondition) DeepCopy() *CapabilityCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(CapabilityCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapabilityOverride) DeepCopyInto(out *CapabilityOverride) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapabilityOverride.
+func (in *CapabilityOverride) DeepCopy() *CapabilityOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(CapabilityOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CapabilityOverride) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapabilityOverrideList) DeepCopyInto(out *CapabilityOverrideList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CapabilityOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapabilityOverrideList.
+func (in *CapabilityOverrideList) DeepCopy() *CapabilityOverrideList {
+	if in == nil {
+		return nil
+	}
+	out := new(CapabilityOverrideList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CapabilityOverrideList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapabilityOverrideRule) DeepCopyInto(out *CapabilityOverrideRule) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]ParameterMatcher, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Features.DeepCopyInto(&out.Features)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapabilityOverrideRule.
+func (in *CapabilityOverrideRule) DeepCopy() *CapabilityOverrideRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CapabilityOverrideRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapabilityOverrideSpec) DeepCopyInto(out *CapabilityOverrideSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]CapabilityOverrideRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapabilityOverrideSpec.
+func (in *CapabilityOverrideSpec) DeepCopy() *CapabilityOverrideSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CapabilityOverrideSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapabilityStatus) DeepCopyInto(out *CapabilityStatus) {
+	*out = *in
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]CapabilityCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(TopologyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UnsupportedFeatures != nil {
+		in, out := &in.UnsupportedFeatures, &out.UnsupportedFeatures
+		*out = make([]UnsupportedFeature, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapabilityStatus.
+func (in *CapabilityStatus) DeepCopy() *CapabilityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CapabilityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeaturesOverride) DeepCopyInto(out *FeaturesOverride) {
+	*out = *in
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Volume != nil {
+		in, out := &in.Volume, &out.Volume
+		*out = new(VolumeFeaturesOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Snapshot != nil {
+		in, out := &in.Snapshot, &out.Snapshot
+		*out = new(SnapshotFeaturesOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Node != nil {
+		in, out := &in.Node, &out.Node
+		*out = new(NodeFeaturesOverride)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeaturesOverride.
+func (in *FeaturesOverride) DeepCopy() *FeaturesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(FeaturesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCapability) DeepCopyInto(out *NodeCapability) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCapability.
+func (in *NodeCapability) DeepCopy() *NodeCapability {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCapability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeCapability) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCapabilityList) DeepCopyInto(out *NodeCapabilityList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeCapability, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCapabilityList.
+func (in *NodeCapabilityList) DeepCopy() *NodeCapabilityList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCapabilityList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeCapabilityList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCapabilitySpec) DeepCopyInto(out *NodeCapabilitySpec) {
+	*out = *in
+	out.PluginInfo = in.PluginInfo
+	if in.AccessibleTopology != nil {
+		in, out := &in.AccessibleTopology, &out.AccessibleTopology
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.Features = in.Features
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCapabilitySpec.
+func (in *NodeCapabilitySpec) DeepCopy() *NodeCapabilitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCapabilitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFeaturesOverride) DeepCopyInto(out *NodeFeaturesOverride) {
+	*out = *in
+	if in.StageUnstage != nil {
+		in, out := &in.StageUnstage, &out.StageUnstage
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Expand != nil {
+		in, out := &in.Expand, &out.Expand
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SingleNodeMultiWriter != nil {
+		in, out := &in.SingleNodeMultiWriter, &out.SingleNodeMultiWriter
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFeaturesOverride.
+func (in *NodeFeaturesOverride) DeepCopy() *NodeFeaturesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFeaturesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterMatcher) DeepCopyInto(out *ParameterMatcher) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterMatcher.
+func (in *ParameterMatcher) DeepCopy() *ParameterMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisionerCapability) DeepCopyInto(out *ProvisionerCapability) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -88,7 +430,7 @@ func (in *ProvisionerCapabilityList) DeepCopyObject() runtime.Object {
 func (in *ProvisionerCapabilitySpec) DeepCopyInto(out *ProvisionerCapabilitySpec) {
 	*out = *in
 	out.PluginInfo = in.PluginInfo
-	out.Features = in.Features
+	in.Features.DeepCopyInto(&out.Features)
 	return
 }
 
@@ -105,8 +447,9 @@ func (in *ProvisionerCapabilitySpec) DeepCopy() *ProvisionerCapabilitySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisionerCapabilitySpecFeatures) DeepCopyInto(out *ProvisionerCapabilitySpecFeatures) {
 	*out = *in
-	out.Volume = in.Volume
+	in.Volume.DeepCopyInto(&out.Volume)
 	out.Snapshot = in.Snapshot
+	out.Node = in.Node
 	return
 }
 
@@ -120,6 +463,22 @@ func (in *ProvisionerCapabilitySpecFeatures) DeepCopy() *ProvisionerCapabilitySp
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionerCapabilitySpecFeaturesNode) DeepCopyInto(out *ProvisionerCapabilitySpecFeaturesNode) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionerCapabilitySpecFeaturesNode.
+func (in *ProvisionerCapabilitySpecFeaturesNode) DeepCopy() *ProvisionerCapabilitySpecFeaturesNode {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionerCapabilitySpecFeaturesNode)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisionerCapabilitySpecFeaturesSnapshot) DeepCopyInto(out *ProvisionerCapabilitySpecFeaturesSnapshot) {
 	*out = *in
@@ -139,6 +498,13 @@ func (in *ProvisionerCapabilitySpecFeaturesSnapshot) DeepCopy() *ProvisionerCapa
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisionerCapabilitySpecFeaturesVolume) DeepCopyInto(out *ProvisionerCapabilitySpecFeaturesVolume) {
 	*out = *in
+	if in.VolumeModes != nil {
+		in, out := &in.VolumeModes, &out.VolumeModes
+		*out = make([]VolumeModeCapability, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -168,12 +534,39 @@ func (in *ProvisionerCapabilitySpecPluginInfo) DeepCopy() *ProvisionerCapability
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotFeaturesOverride) DeepCopyInto(out *SnapshotFeaturesOverride) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(bool)
+		**out = **in
+	}
+	if in.List != nil {
+		in, out := &in.List, &out.List
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotFeaturesOverride.
+func (in *SnapshotFeaturesOverride) DeepCopy() *SnapshotFeaturesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotFeaturesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageClassCapability) DeepCopyInto(out *StorageClassCapability) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -231,7 +624,12 @@ func (in *StorageClassCapabilityList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageClassCapabilitySpec) DeepCopyInto(out *StorageClassCapabilitySpec) {
 	*out = *in
-	out.Features = in.Features
+	in.Features.DeepCopyInto(&out.Features)
+	if in.SnapshotClassNames != nil {
+		in, out := &in.SnapshotClassNames, &out.SnapshotClassNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -248,8 +646,9 @@ func (in *StorageClassCapabilitySpec) DeepCopy() *StorageClassCapabilitySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageClassCapabilitySpecFeatures) DeepCopyInto(out *StorageClassCapabilitySpecFeatures) {
 	*out = *in
-	out.Volume = in.Volume
+	in.Volume.DeepCopyInto(&out.Volume)
 	out.Snapshot = in.Snapshot
+	out.Node = in.Node
 	return
 }
 
@@ -262,3 +661,162 @@ func (in *StorageClassCapabilitySpecFeatures) DeepCopy() *StorageClassCapability
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyStatus) DeepCopyInto(out *TopologyStatus) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Segments != nil {
+		in, out := &in.Segments, &out.Segments
+		*out = make([]map[string]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyStatus.
+func (in *TopologyStatus) DeepCopy() *TopologyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnsupportedFeature) DeepCopyInto(out *UnsupportedFeature) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnsupportedFeature.
+func (in *UnsupportedFeature) DeepCopy() *UnsupportedFeature {
+	if in == nil {
+		return nil
+	}
+	out := new(UnsupportedFeature)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeFeaturesOverride) DeepCopyInto(out *VolumeFeaturesOverride) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Attach != nil {
+		in, out := &in.Attach, &out.Attach
+		*out = new(bool)
+		**out = **in
+	}
+	if in.List != nil {
+		in, out := &in.List, &out.List
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Stats != nil {
+		in, out := &in.Stats, &out.Stats
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Expand != nil {
+		in, out := &in.Expand, &out.Expand
+		*out = new(ExpandMode)
+		**out = **in
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ReadOnlyAttach != nil {
+		in, out := &in.ReadOnlyAttach, &out.ReadOnlyAttach
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ControllerExpand != nil {
+		in, out := &in.ControllerExpand, &out.ControllerExpand
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ListPublishedNodes != nil {
+		in, out := &in.ListPublishedNodes, &out.ListPublishedNodes
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Get != nil {
+		in, out := &in.Get, &out.Get
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SingleNodeMultiWriter != nil {
+		in, out := &in.SingleNodeMultiWriter, &out.SingleNodeMultiWriter
+		*out = new(bool)
+		**out = **in
+	}
+	if in.VolumeModes != nil {
+		in, out := &in.VolumeModes, &out.VolumeModes
+		*out = make([]VolumeModeCapability, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeFeaturesOverride.
+func (in *VolumeFeaturesOverride) DeepCopy() *VolumeFeaturesOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeFeaturesOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeModeCapability) DeepCopyInto(out *VolumeModeCapability) {
+	*out = *in
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]v1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeModeCapability.
+func (in *VolumeModeCapability) DeepCopy() *VolumeModeCapability {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeModeCapability)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,257 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package v1beta1
+
+import (
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RegisterConversions adds conversion functions between v1alpha1 and v1beta1 to the given scheme.
+// v1alpha1 is the storage version, so every v1beta1 object must round-trip through it without loss.
+func RegisterConversions(s *runtime.Scheme) error {
+	if err := s.AddConversionFunc((*v1alpha1.ProvisionerCapability)(nil), (*ProvisionerCapability)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ProvisionerCapability_To_v1beta1_ProvisionerCapability(a.(*v1alpha1.ProvisionerCapability), b.(*ProvisionerCapability), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*ProvisionerCapability)(nil), (*v1alpha1.ProvisionerCapability)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_ProvisionerCapability_To_v1alpha1_ProvisionerCapability(a.(*ProvisionerCapability), b.(*v1alpha1.ProvisionerCapability), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha1.StorageClassCapability)(nil), (*StorageClassCapability)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_StorageClassCapability_To_v1beta1_StorageClassCapability(a.(*v1alpha1.StorageClassCapability), b.(*StorageClassCapability), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*StorageClassCapability)(nil), (*v1alpha1.StorageClassCapability)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1beta1_StorageClassCapability_To_v1alpha1_StorageClassCapability(a.(*StorageClassCapability), b.(*v1alpha1.StorageClassCapability), scope)
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func Convert_v1alpha1_ProvisionerCapability_To_v1beta1_ProvisionerCapability(in *v1alpha1.ProvisionerCapability, out *ProvisionerCapability, s conversion.Scope) error {
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.PluginInfo = PluginInfo{
+		Name:    in.Spec.PluginInfo.Name,
+		Version: in.Spec.PluginInfo.Version,
+	}
+	out.Spec.Features = Features{
+		Topology: TopologyFeatures{Supported: in.Spec.Features.Topology},
+		Volume:   convertVolumeFromV1alpha1(in.Spec.Features.Volume),
+		Snapshot: convertSnapshotFromV1alpha1(in.Spec.Features.Snapshot),
+		Node:     convertNodeFromV1alpha1(in.Spec.Features.Node),
+	}
+	convertStatusFromV1alpha1(&in.Status, &out.Status)
+	return nil
+}
+
+func Convert_v1beta1_ProvisionerCapability_To_v1alpha1_ProvisionerCapability(in *ProvisionerCapability, out *v1alpha1.ProvisionerCapability, s conversion.Scope) error {
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.PluginInfo = v1alpha1.ProvisionerCapabilitySpecPluginInfo{
+		Name:    in.Spec.PluginInfo.Name,
+		Version: in.Spec.PluginInfo.Version,
+	}
+	out.Spec.Features = v1alpha1.ProvisionerCapabilitySpecFeatures{
+		Topology: in.Spec.Features.Topology.Supported,
+		Volume:   convertVolumeToV1alpha1(in.Spec.Features.Volume),
+		Snapshot: convertSnapshotToV1alpha1(in.Spec.Features.Snapshot),
+		Node:     convertNodeToV1alpha1(in.Spec.Features.Node),
+	}
+	convertStatusToV1alpha1(&in.Status, &out.Status)
+	return nil
+}
+
+func Convert_v1alpha1_StorageClassCapability_To_v1beta1_StorageClassCapability(in *v1alpha1.StorageClassCapability, out *StorageClassCapability, s conversion.Scope) error {
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Provisioner = in.Spec.Provisioner
+	out.Spec.Features = Features{
+		Topology: TopologyFeatures{Supported: in.Spec.Features.Topology},
+		Volume:   convertVolumeFromV1alpha1(in.Spec.Features.Volume),
+		Snapshot: convertSnapshotFromV1alpha1(in.Spec.Features.Snapshot),
+		Node:     convertNodeFromV1alpha1(in.Spec.Features.Node),
+	}
+	out.Spec.SnapshotClassName = in.Spec.SnapshotClassName
+	out.Spec.SnapshotClassNames = append([]string(nil), in.Spec.SnapshotClassNames...)
+	convertStatusFromV1alpha1(&in.Status, &out.Status)
+	return nil
+}
+
+func Convert_v1beta1_StorageClassCapability_To_v1alpha1_StorageClassCapability(in *StorageClassCapability, out *v1alpha1.StorageClassCapability, s conversion.Scope) error {
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec.Provisioner = in.Spec.Provisioner
+	out.Spec.Features = v1alpha1.StorageClassCapabilitySpecFeatures{
+		Topology: in.Spec.Features.Topology.Supported,
+		Volume:   convertVolumeToV1alpha1(in.Spec.Features.Volume),
+		Snapshot: convertSnapshotToV1alpha1(in.Spec.Features.Snapshot),
+		Node:     convertNodeToV1alpha1(in.Spec.Features.Node),
+	}
+	out.Spec.SnapshotClassName = in.Spec.SnapshotClassName
+	out.Spec.SnapshotClassNames = append([]string(nil), in.Spec.SnapshotClassNames...)
+	convertStatusToV1alpha1(&in.Status, &out.Status)
+	return nil
+}
+
+func convertVolumeFromV1alpha1(in v1alpha1.ProvisionerCapabilitySpecFeaturesVolume) VolumeFeatures {
+	return VolumeFeatures{
+		Create:     in.Create,
+		Attach:     in.Attach,
+		List:       in.List,
+		Clone:      in.Clone,
+		Stats:      in.Stats,
+		ExpandMode: ExpandMode(in.Expand),
+
+		Capacity:              in.Capacity,
+		ReadOnlyAttach:        in.ReadOnlyAttach,
+		ControllerExpand:      in.ControllerExpand,
+		ListPublishedNodes:    in.ListPublishedNodes,
+		Condition:             in.Condition,
+		Get:                   in.Get,
+		SingleNodeMultiWriter: in.SingleNodeMultiWriter,
+		VolumeModes:           convertVolumeModesFromV1alpha1(in.VolumeModes),
+	}
+}
+
+// VolumeModeCapability has the same layout in both versions.
+func convertVolumeModesFromV1alpha1(in []v1alpha1.VolumeModeCapability) []VolumeModeCapability {
+	if in == nil {
+		return nil
+	}
+	out := make([]VolumeModeCapability, len(in))
+	for i := range in {
+		out[i] = VolumeModeCapability(*in[i].DeepCopy())
+	}
+	return out
+}
+
+func convertVolumeToV1alpha1(in VolumeFeatures) v1alpha1.ProvisionerCapabilitySpecFeaturesVolume {
+	return v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{
+		Create: in.Create,
+		Attach: in.Attach,
+		List:   in.List,
+		Clone:  in.Clone,
+		Stats:  in.Stats,
+		Expand: v1alpha1.ExpandMode(in.ExpandMode),
+
+		Capacity:              in.Capacity,
+		ReadOnlyAttach:        in.ReadOnlyAttach,
+		ControllerExpand:      in.ControllerExpand,
+		ListPublishedNodes:    in.ListPublishedNodes,
+		Condition:             in.Condition,
+		Get:                   in.Get,
+		SingleNodeMultiWriter: in.SingleNodeMultiWriter,
+		VolumeModes:           convertVolumeModesToV1alpha1(in.VolumeModes),
+	}
+}
+
+func convertVolumeModesToV1alpha1(in []VolumeModeCapability) []v1alpha1.VolumeModeCapability {
+	if in == nil {
+		return nil
+	}
+	out := make([]v1alpha1.VolumeModeCapability, len(in))
+	for i := range in {
+		out[i] = v1alpha1.VolumeModeCapability(*in[i].DeepCopy())
+	}
+	return out
+}
+
+func convertSnapshotFromV1alpha1(in v1alpha1.ProvisionerCapabilitySpecFeaturesSnapshot) SnapshotFeatures {
+	return SnapshotFeatures{
+		Create: in.Create,
+		List:   in.List,
+	}
+}
+
+func convertSnapshotToV1alpha1(in SnapshotFeatures) v1alpha1.ProvisionerCapabilitySpecFeaturesSnapshot {
+	return v1alpha1.ProvisionerCapabilitySpecFeaturesSnapshot{
+		Create: in.Create,
+		List:   in.List,
+	}
+}
+
+func convertNodeFromV1alpha1(in v1alpha1.ProvisionerCapabilitySpecFeaturesNode) NodeFeatures {
+	return NodeFeatures{
+		StageUnstage:          in.StageUnstage,
+		Expand:                in.Expand,
+		Condition:             in.Condition,
+		SingleNodeMultiWriter: in.SingleNodeMultiWriter,
+	}
+}
+
+func convertNodeToV1alpha1(in NodeFeatures) v1alpha1.ProvisionerCapabilitySpecFeaturesNode {
+	return v1alpha1.ProvisionerCapabilitySpecFeaturesNode{
+		StageUnstage:          in.StageUnstage,
+		Expand:                in.Expand,
+		Condition:             in.Condition,
+		SingleNodeMultiWriter: in.SingleNodeMultiWriter,
+	}
+}
+
+func convertStatusFromV1alpha1(in *v1alpha1.CapabilityStatus, out *CapabilityStatus) {
+	out.ObservedGeneration = in.ObservedGeneration
+	out.LastProbeTime = in.LastProbeTime.DeepCopy()
+	// TopologyStatus has the same layout in both versions.
+	out.Topology = (*TopologyStatus)(in.Topology.DeepCopy())
+	out.UnsupportedFeatures = nil
+	for _, f := range in.UnsupportedFeatures {
+		out.UnsupportedFeatures = append(out.UnsupportedFeatures, UnsupportedFeature(f))
+	}
+	out.Conditions = nil
+	if in.Conditions != nil {
+		out.Conditions = make([]CapabilityCondition, len(in.Conditions))
+		for i, c := range in.Conditions {
+			out.Conditions[i] = CapabilityCondition{
+				Type:               CapabilityConditionType(c.Type),
+				Status:             c.Status,
+				ObservedGeneration: c.ObservedGeneration,
+				LastTransitionTime: c.LastTransitionTime,
+				Reason:             c.Reason,
+				Message:            c.Message,
+			}
+		}
+	}
+}
+
+func convertStatusToV1alpha1(in *CapabilityStatus, out *v1alpha1.CapabilityStatus) {
+	out.ObservedGeneration = in.ObservedGeneration
+	out.LastProbeTime = in.LastProbeTime.DeepCopy()
+	out.Topology = (*v1alpha1.TopologyStatus)(in.Topology.DeepCopy())
+	out.UnsupportedFeatures = nil
+	for _, f := range in.UnsupportedFeatures {
+		out.UnsupportedFeatures = append(out.UnsupportedFeatures, v1alpha1.UnsupportedFeature(f))
+	}
+	out.Conditions = nil
+	if in.Conditions != nil {
+		out.Conditions = make([]v1alpha1.CapabilityCondition, len(in.Conditions))
+		for i, c := range in.Conditions {
+			out.Conditions[i] = v1alpha1.CapabilityCondition{
+				Type:               v1alpha1.CapabilityConditionType(c.Type),
+				Status:             c.Status,
+				ObservedGeneration: c.ObservedGeneration,
+				LastTransitionTime: c.LastTransitionTime,
+				Reason:             c.Reason,
+				Message:            c.Message,
+			}
+		}
+	}
+}
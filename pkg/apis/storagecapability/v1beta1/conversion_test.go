@@ -0,0 +1,90 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package v1beta1
+
+import (
+	fuzz "github.com/google/gofuzz"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"testing"
+)
+
+const fuzzIters = 200
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(0.2).NumElements(0, 3).Funcs(
+		// TypeMeta is set by the serializer, not by the conversion functions.
+		func(j *metav1.TypeMeta, c fuzz.Continue) {},
+	)
+}
+
+// roundTrip converts in to the hub and back, and expects to get the same object.
+func roundTrip(t *testing.T, scheme *runtime.Scheme, in, hub, out runtime.Object) {
+	if err := scheme.Convert(in, hub, nil); err != nil {
+		t.Fatalf("convert %T to %T error: %v", in, hub, err)
+	}
+	if err := scheme.Convert(hub, out, nil); err != nil {
+		t.Fatalf("convert %T to %T error: %v", hub, out, err)
+	}
+	if !apiequality.Semantic.DeepEqual(in, out) {
+		t.Errorf("%T is not equal after round trip through %T\nDiff:\n %s", in, hub, diff.ObjectGoPrintSideBySide(in, out))
+	}
+}
+
+func TestProvisionerCapabilityRoundTrip(t *testing.T) {
+	scheme := newScheme(t)
+	f := newFuzzer()
+	for i := 0; i < fuzzIters; i++ {
+		alpha := &v1alpha1.ProvisionerCapability{}
+		f.Fuzz(alpha)
+		roundTrip(t, scheme, alpha, &ProvisionerCapability{}, &v1alpha1.ProvisionerCapability{})
+
+		beta := &ProvisionerCapability{}
+		f.Fuzz(beta)
+		roundTrip(t, scheme, beta, &v1alpha1.ProvisionerCapability{}, &ProvisionerCapability{})
+	}
+}
+
+func TestStorageClassCapabilityRoundTrip(t *testing.T) {
+	scheme := newScheme(t)
+	f := newFuzzer()
+	for i := 0; i < fuzzIters; i++ {
+		alpha := &v1alpha1.StorageClassCapability{}
+		f.Fuzz(alpha)
+		roundTrip(t, scheme, alpha, &StorageClassCapability{}, &v1alpha1.StorageClassCapability{})
+
+		beta := &StorageClassCapability{}
+		f.Fuzz(beta)
+		roundTrip(t, scheme, beta, &v1alpha1.StorageClassCapability{}, &StorageClassCapability{})
+	}
+}
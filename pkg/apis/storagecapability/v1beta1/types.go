@@ -0,0 +1,175 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ProvisionerCapability struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProvisionerCapabilitySpec `json:"spec"`
+	Status CapabilityStatus          `json:"status,omitempty"`
+}
+
+type ProvisionerCapabilitySpec struct {
+	PluginInfo PluginInfo `json:"pluginInfo"`
+	Features   Features   `json:"features"`
+}
+
+type PluginInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Features is shared by ProvisionerCapability and StorageClassCapability.
+type Features struct {
+	Topology TopologyFeatures `json:"topology"`
+	Volume   VolumeFeatures   `json:"volume"`
+	Snapshot SnapshotFeatures `json:"snapshot"`
+	Node     NodeFeatures     `json:"node"`
+}
+
+type TopologyFeatures struct {
+	// Supported is true if the plugin reports VOLUME_ACCESSIBILITY_CONSTRAINTS.
+	Supported bool `json:"supported"`
+}
+
+type VolumeFeatures struct {
+	Create     bool       `json:"create"`
+	Attach     bool       `json:"attach"`
+	List       bool       `json:"list"`
+	Clone      bool       `json:"clone"`
+	Stats      bool       `json:"stats"`
+	ExpandMode ExpandMode `json:"expandMode"`
+	// The fields below are determined by ControllerGetCapabilities in ControllerServer.
+	Capacity              bool                   `json:"capacity"`
+	ReadOnlyAttach        bool                   `json:"readOnlyAttach"`
+	ControllerExpand      bool                   `json:"controllerExpand"`
+	ListPublishedNodes    bool                   `json:"listPublishedNodes"`
+	Condition             bool                   `json:"condition"`
+	Get                   bool                   `json:"get"`
+	SingleNodeMultiWriter bool                   `json:"singleNodeMultiWriter"`
+	VolumeModes           []VolumeModeCapability `json:"volumeModes,omitempty"`
+}
+
+type VolumeModeCapability struct {
+	VolumeMode  corev1.PersistentVolumeMode         `json:"volumeMode"`
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+}
+
+type SnapshotFeatures struct {
+	Create bool `json:"create"`
+	List   bool `json:"list"`
+}
+
+// NodeFeatures is determined by NodeGetCapabilities in NodeServer.
+type NodeFeatures struct {
+	StageUnstage          bool `json:"stageUnstage"`
+	Expand                bool `json:"expand"`
+	Condition             bool `json:"condition"`
+	SingleNodeMultiWriter bool `json:"singleNodeMultiWriter"`
+}
+
+type ExpandMode string
+
+const (
+	ExpandModeUnknown ExpandMode = "UNKNOWN"
+	ExpandModeOffline ExpandMode = "OFFLINE"
+	ExpandModeOnline  ExpandMode = "ONLINE"
+)
+
+// CapabilityStatus tells whether the capability data is fresh, stale or failed to probe.
+type CapabilityStatus struct {
+	ObservedGeneration  int64                 `json:"observedGeneration,omitempty"`
+	LastProbeTime       *metav1.Time          `json:"lastProbeTime,omitempty"`
+	Conditions          []CapabilityCondition `json:"conditions,omitempty"`
+	Topology            *TopologyStatus       `json:"topology,omitempty"`
+	UnsupportedFeatures []UnsupportedFeature  `json:"unsupportedFeatures,omitempty"`
+}
+
+type UnsupportedFeature struct {
+	Feature string `json:"feature"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+type TopologyStatus struct {
+	Keys     []string            `json:"keys,omitempty"`
+	Segments []map[string]string `json:"segments,omitempty"`
+}
+
+type CapabilityConditionType string
+
+const (
+	CapabilityReady               CapabilityConditionType = "Ready"
+	CapabilityProbeFailed         CapabilityConditionType = "ProbeFailed"
+	CapabilityStale               CapabilityConditionType = "Stale"
+	CapabilityProvisionerMissing  CapabilityConditionType = "ProvisionerMissing"
+	CapabilitySnapshotUnavailable CapabilityConditionType = "SnapshotUnavailable"
+)
+
+type CapabilityCondition struct {
+	Type               CapabilityConditionType `json:"type"`
+	Status             metav1.ConditionStatus  `json:"status"`
+	ObservedGeneration int64                   `json:"observedGeneration,omitempty"`
+	LastTransitionTime metav1.Time             `json:"lastTransitionTime,omitempty"`
+	Reason             string                  `json:"reason,omitempty"`
+	Message            string                  `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ProvisionerCapabilityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ProvisionerCapability `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StorageClassCapability struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StorageClassCapabilitySpec `json:"spec"`
+	Status CapabilityStatus           `json:"status,omitempty"`
+}
+
+type StorageClassCapabilitySpec struct {
+	Provisioner        string   `json:"provisioner"`
+	Features           Features `json:"features"`
+	SnapshotClassName  string   `json:"snapshotClassName,omitempty"`
+	SnapshotClassNames []string `json:"snapshotClassNames,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StorageClassCapabilityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []StorageClassCapability `json:"items"`
+}
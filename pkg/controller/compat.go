@@ -0,0 +1,213 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package controller
+
+import (
+	"fmt"
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	"reflect"
+	"strings"
+)
+
+const (
+	// ReasonKubernetesVersionTooOld means the server version is older than the first version supporting the feature.
+	ReasonKubernetesVersionTooOld = "KubernetesVersionTooOld"
+	// ReasonAPINotServed means none of the APIs required by the feature is served.
+	ReasonAPINotServed = "APINotServed"
+	// ReasonDiscoveryFailed means the server version or the served APIs could not be discovered yet.
+	ReasonDiscoveryFailed = "DiscoveryFailed"
+)
+
+// featureRequirement is a row of the compatibility matrix.
+type featureRequirement struct {
+	// feature is the path of the feature in spec.features.
+	feature string
+	// minVersion is the first Kubernetes version enabling the feature by default. Empty if there is none.
+	minVersion string
+	// resources are the APIs of the feature, any of which must be served. Empty if no API is required.
+	resources []schema.GroupVersionResource
+	// disable turns the feature off in StorageClassCapability.
+	disable func(features *crdapi.StorageClassCapabilitySpecFeatures)
+}
+
+// featureMatrix lists the features of StorageClassCapability depending on the Kubernetes cluster.
+var featureMatrix = []featureRequirement{
+	{
+		// VolumeSnapshot is beta since v1.17 and GA since v1.20, its CRDs are installed separately.
+		feature:    "snapshot",
+		minVersion: "v1.17.0",
+		resources:  []schema.GroupVersionResource{snapshot.ClassResource(snapshot.V1), snapshot.ClassResource(snapshot.V1beta1)},
+		disable: func(features *crdapi.StorageClassCapabilitySpecFeatures) {
+			features.Snapshot = crdapi.ProvisionerCapabilitySpecFeaturesSnapshot{}
+		},
+	},
+	{
+		// CSI volume expansion is beta since v1.16 and GA since v1.24.
+		feature:    "volume.expandMode",
+		minVersion: "v1.16.0",
+		disable: func(features *crdapi.StorageClassCapabilitySpecFeatures) {
+			features.Volume.Expand = crdapi.ExpandModeUnknown
+		},
+	},
+	{
+		// CSI volume cloning is beta since v1.16 and GA since v1.18.
+		feature:    "volume.clone",
+		minVersion: "v1.16.0",
+		disable: func(features *crdapi.StorageClassCapabilitySpecFeatures) {
+			features.Volume.Clone = false
+		},
+	},
+	{
+		// Storage capacity tracking is alpha in v1.19, beta in v1.21 and GA in v1.24.
+		feature: "volume.capacity",
+		resources: []schema.GroupVersionResource{
+			{Group: "storage.k8s.io", Version: "v1", Resource: "csistoragecapacities"},
+			{Group: "storage.k8s.io", Version: "v1beta1", Resource: "csistoragecapacities"},
+			{Group: "storage.k8s.io", Version: "v1alpha1", Resource: "csistoragecapacities"},
+		},
+		disable: func(features *crdapi.StorageClassCapabilitySpecFeatures) {
+			features.Volume.Capacity = false
+		},
+	},
+}
+
+// compatibility tells which features of the matrix are not supported by the Kubernetes cluster.
+// A nil compatibility supports all features.
+type compatibility struct {
+	serverVersion string
+	unsupported   []crdapi.UnsupportedFeature
+}
+
+// discoverCompatibility evaluates the feature matrix against the server version and the served APIs.
+func discoverCompatibility(client discovery.DiscoveryInterface) (*compatibility, error) {
+	info, err := client.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	servedGroupVersions := map[string]bool{}
+	for _, group := range groups.Groups {
+		for _, v := range group.Versions {
+			servedGroupVersions[v.GroupVersion] = true
+		}
+	}
+	// Only the group versions of the matrix are looked up.
+	servedResources := map[schema.GroupVersionResource]bool{}
+	for _, req := range featureMatrix {
+		for _, gvr := range req.resources {
+			gv := gvr.GroupVersion().String()
+			if !servedGroupVersions[gv] {
+				continue
+			}
+			resources, err := client.ServerResourcesForGroupVersion(gv)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range resources.APIResources {
+				servedResources[gvr.GroupVersion().WithResource(r.Name)] = true
+			}
+		}
+	}
+	return newCompatibility(serverVersion, servedResources), nil
+}
+
+// discoveryFailedCompatibility disables every feature of the matrix until the cluster is discovered, so no
+// StorageClassCapability reports a feature the cluster may not support.
+func discoveryFailedCompatibility(err error) *compatibility {
+	compat := &compatibility{}
+	for _, req := range featureMatrix {
+		compat.unsupported = append(compat.unsupported, crdapi.UnsupportedFeature{
+			Feature: req.feature,
+			Reason:  ReasonDiscoveryFailed,
+			Message: fmt.Sprintf("Discovering the features supported by the cluster failed: %s", err),
+		})
+	}
+	return compat
+}
+
+func newCompatibility(serverVersion *version.Version, servedResources map[schema.GroupVersionResource]bool) *compatibility {
+	compat := &compatibility{serverVersion: serverVersion.String()}
+	for _, req := range featureMatrix {
+		if req.minVersion != "" {
+			minVersion := version.MustParseGeneric(req.minVersion)
+			if !serverVersion.AtLeast(minVersion) {
+				compat.unsupported = append(compat.unsupported, crdapi.UnsupportedFeature{
+					Feature: req.feature,
+					Reason:  ReasonKubernetesVersionTooOld,
+					Message: fmt.Sprintf("Requires Kubernetes %s or later, the server is v%s", req.minVersion, compat.serverVersion),
+				})
+				continue
+			}
+		}
+		if len(req.resources) == 0 {
+			continue
+		}
+		served := false
+		var names []string
+		for _, gvr := range req.resources {
+			served = served || servedResources[gvr]
+			names = append(names, gvr.Resource+"."+gvr.GroupVersion().String())
+		}
+		if !served {
+			compat.unsupported = append(compat.unsupported, crdapi.UnsupportedFeature{
+				Feature: req.feature,
+				Reason:  ReasonAPINotServed,
+				Message: fmt.Sprintf("Requires one of %s to be served", strings.Join(names, ", ")),
+			})
+		}
+	}
+	return compat
+}
+
+// unsupportedFeatures returns the features disabled for all StorageClassCapabilities.
+func (c *compatibility) unsupportedFeatures() []crdapi.UnsupportedFeature {
+	if c == nil {
+		return nil
+	}
+	return c.unsupported
+}
+
+// restrict disables the features the cluster does not support.
+func (c *compatibility) restrict(sccap *crdapi.StorageClassCapability) {
+	if c == nil || sccap == nil {
+		return
+	}
+	for _, f := range c.unsupported {
+		for _, req := range featureMatrix {
+			if req.feature == f.Feature {
+				req.disable(&sccap.Spec.Features)
+			}
+		}
+	}
+}
+
+func (c *compatibility) equal(other *compatibility) bool {
+	return reflect.DeepEqual(c.unsupportedFeatures(), other.unsupportedFeatures())
+}
@@ -20,28 +20,31 @@ package controller
 
 import (
 	"fmt"
-	snapapi "github.com/kubernetes-csi/external-snapshotter/v2/pkg/apis/volumesnapshot/v1beta1"
-	snapinformers "github.com/kubernetes-csi/external-snapshotter/v2/pkg/client/informers/externalversions/volumesnapshot/v1beta1"
-	snaplisters "github.com/kubernetes-csi/external-snapshotter/v2/pkg/client/listers/volumesnapshot/v1beta1"
 	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
 	clientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
 	crdscheme "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/scheme"
 	crdinformers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions/storagecapability/v1alpha1"
 	crdlisters "github.com/kubesphere/storage-capability/pkg/generated/listers/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	scinformers "k8s.io/client-go/informers/storage/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	sclisters "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
+	"reflect"
+	"sync"
 	"time"
 )
 
@@ -54,7 +57,13 @@ const (
 
 	MessageResourceSynced = "StorageClassCapability synced successfully"
 
-	MinimalKubernetesVersion = "v1.17.0"
+	// The compatibility matrix is evaluated again every CompatibilityResyncPeriod to catch server upgrades.
+	CompatibilityResyncPeriod = 10 * time.Minute
+	// compatibilityRetryDelay is the first delay to retry a failed discovery of the compatibility matrix.
+	compatibilityRetryDelay = time.Second
+
+	// ProvisionerCapability and NodeCapability which have not been probed for StaleThreshold are regarded as stale.
+	StaleThreshold = 10 * time.Minute
 )
 
 type Controller struct {
@@ -64,7 +73,9 @@ type Controller struct {
 	scLister sclisters.StorageClassLister
 	scSynced cache.InformerSynced
 
-	snapLister snaplisters.VolumeSnapshotClassLister
+	// snapLister is nil while snapshot.storage.k8s.io is not served, see WatchSnapshotClasses.
+	snapMutex  sync.RWMutex
+	snapLister snapshot.ClassLister
 	snapSynced cache.InformerSynced
 
 	pcapLister crdlisters.ProvisionerCapabilityLister
@@ -73,34 +84,55 @@ type Controller struct {
 	sccapLister crdlisters.StorageClassCapabilityLister
 	sccapSynced cache.InformerSynced
 
+	ncapLister crdlisters.NodeCapabilityLister
+	ncapSynced cache.InformerSynced
+
+	nodeLister corelisters.NodeLister
+	nodeSynced cache.InformerSynced
+
+	overrideLister crdlisters.CapabilityOverrideLister
+	overrideSynced cache.InformerSynced
+
+	// compat is nil until the cluster is discovered in Run, all features are supported then.
+	compatMutex sync.RWMutex
+	compat      *compatibility
+
 	workqueue workqueue.RateLimitingInterface
 }
 
 // This controller is responsible to watch StorageClass, SnapshotClass, StorageClassCapability CRD and ProvisionerCapability CRD.
 // And then update StorageClassCapability CRD resource object to the newest status.
+// The snapInformer is nil if snapshot.storage.k8s.io is not served, snapshot features are reported unavailable then.
 func NewController(
 	kubeclientset kubernetes.Interface,
 	crdclientset clientset.Interface,
 	scInformer scinformers.StorageClassInformer,
-	snapInformer snapinformers.VolumeSnapshotClassInformer,
+	snapInformer snapshot.ClassInformer,
 	pcapInformer crdinformers.ProvisionerCapabilityInformer,
 	sccapInformer crdinformers.StorageClassCapabilityInformer,
+	ncapInformer crdinformers.NodeCapabilityInformer,
+	overrideInformer crdinformers.CapabilityOverrideInformer,
+	nodeInformer coreinformers.NodeInformer,
 ) *Controller {
 	utilruntime.Must(crdscheme.AddToScheme(scheme.Scheme))
 	klog.V(4).Info("Creating event broadcaster")
 
 	controller := &Controller{
-		kubeclientset: kubeclientset,
-		crdclientset:  crdclientset,
-		scLister:      scInformer.Lister(),
-		scSynced:      scInformer.Informer().HasSynced,
-		snapLister:    snapInformer.Lister(),
-		snapSynced:    snapInformer.Informer().HasSynced,
-		pcapLister:    pcapInformer.Lister(),
-		pcapSynced:    pcapInformer.Informer().HasSynced,
-		sccapLister:   sccapInformer.Lister(),
-		sccapSynced:   sccapInformer.Informer().HasSynced,
-		workqueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ProvisionerCapability"),
+		kubeclientset:  kubeclientset,
+		crdclientset:   crdclientset,
+		scLister:       scInformer.Lister(),
+		scSynced:       scInformer.Informer().HasSynced,
+		pcapLister:     pcapInformer.Lister(),
+		pcapSynced:     pcapInformer.Informer().HasSynced,
+		sccapLister:    sccapInformer.Lister(),
+		sccapSynced:    sccapInformer.Informer().HasSynced,
+		ncapLister:     ncapInformer.Lister(),
+		ncapSynced:     ncapInformer.Informer().HasSynced,
+		nodeLister:     nodeInformer.Lister(),
+		nodeSynced:     nodeInformer.Informer().HasSynced,
+		overrideLister: overrideInformer.Lister(),
+		overrideSynced: overrideInformer.Informer().HasSynced,
+		workqueue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ProvisionerCapability"),
 	}
 
 	klog.Info("Setting up event handlers")
@@ -119,6 +151,39 @@ func NewController(
 		},
 		DeleteFunc: controller.enqueuePcap,
 	})
+	ncapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueNcap,
+		UpdateFunc: func(old, new interface{}) {
+			newNcap := new.(*crdapi.NodeCapability)
+			oldNcap := old.(*crdapi.NodeCapability)
+			now := time.Now()
+			if reflect.DeepEqual(newNcap.Spec.AccessibleTopology, oldNcap.Spec.AccessibleTopology) &&
+				nodeCapabilityReady(newNcap, now) == nodeCapabilityReady(oldNcap, now) {
+				// Only topology and readiness of NodeCapability are used by the controller.
+				return
+			}
+			controller.enqueueNcap(new)
+		},
+		DeleteFunc: controller.enqueueNcap,
+	})
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: controller.handleNodeObject,
+	})
+	overrideInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: controller.enqueueOverride,
+		UpdateFunc: func(old, new interface{}) {
+			newOverride := new.(*crdapi.CapabilityOverride)
+			oldOverride := old.(*crdapi.CapabilityOverride)
+			if newOverride.ResourceVersion == oldOverride.ResourceVersion {
+				return
+			}
+			if newOverride.Spec.Provisioner != oldOverride.Spec.Provisioner {
+				controller.enqueueOverride(old)
+			}
+			controller.enqueueOverride(new)
+		},
+		DeleteFunc: controller.enqueueOverride,
+	})
 	scInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: controller.handleScObject,
 		UpdateFunc: func(old, new interface{}) {
@@ -133,21 +198,64 @@ func NewController(
 		},
 		DeleteFunc: controller.handleScObject,
 	})
+	if snapInformer != nil {
+		controller.addSnapClassHandler(snapInformer)
+		controller.snapLister = snapInformer.Lister()
+		controller.snapSynced = snapInformer.Informer().HasSynced
+	}
+	return controller
+}
+
+// addSnapClassHandler enqueues the StorageClasses of the driver when a VolumeSnapshotClass changes.
+func (c *Controller) addSnapClassHandler(snapInformer snapshot.ClassInformer) {
 	snapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: controller.handleScObject,
+		AddFunc: c.enqueueSnapClass,
 		UpdateFunc: func(old, new interface{}) {
-			newDepl := new.(*snapapi.VolumeSnapshotClass)
-			oldDepl := old.(*snapapi.VolumeSnapshotClass)
-			if newDepl.ResourceVersion == oldDepl.ResourceVersion {
-				// Periodic resync will send update events for all known Deployments.
-				// Two different versions of the same Deployment will always have different RVs.
+			newSnapClass, err := snapshot.ClassFromObject(new)
+			if err != nil {
+				utilruntime.HandleError(err)
 				return
 			}
-			controller.handleScObject(new)
+			oldSnapClass, err := snapshot.ClassFromObject(old)
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			if newSnapClass.ResourceVersion == oldSnapClass.ResourceVersion {
+				return
+			}
+			if newSnapClass.Driver != oldSnapClass.Driver {
+				c.enqueueSnapClass(old)
+			}
+			c.enqueueSnapClass(new)
 		},
-		DeleteFunc: controller.handleScObject,
+		DeleteFunc: c.enqueueSnapClass,
 	})
-	return controller
+}
+
+// WatchSnapshotClasses starts using the VolumeSnapshotClass informer once snapshot.storage.k8s.io is served
+// after the controller has been started. The informer must be started by the caller. All StorageClasses are
+// synced again when the informer cache is synced.
+func (c *Controller) WatchSnapshotClasses(snapInformer snapshot.ClassInformer, stopCh <-chan struct{}) {
+	c.addSnapClassHandler(snapInformer)
+	go func() {
+		if ok := cache.WaitForCacheSync(stopCh, snapInformer.Informer().HasSynced); !ok {
+			utilruntime.HandleError(fmt.Errorf("failed to wait for VolumeSnapshotClass cache to sync"))
+			return
+		}
+		c.snapMutex.Lock()
+		c.snapLister = snapInformer.Lister()
+		c.snapMutex.Unlock()
+		klog.Info("Watching VolumeSnapshotClasses")
+		c.enqueueAllStorageClasses()
+	}()
+}
+
+// getSnapLister returns nil if VolumeSnapshotClasses are not watched.
+func (c *Controller) getSnapLister() snapshot.ClassLister {
+	c.snapMutex.RLock()
+	defer c.snapMutex.RUnlock()
+	return c.snapLister
 }
 
 func (c *Controller) Start(stopCh <-chan struct{}) error {
@@ -171,6 +279,96 @@ func (c *Controller) enqueuePcap(obj interface{}) {
 		utilruntime.HandleError(err)
 		return
 	}
+	c.enqueueProvisioner(provisioner)
+}
+
+// enqueueNcap enqueues all StorageClasses of the driver, because the topology of the driver may be changed.
+func (c *Controller) enqueueNcap(obj interface{}) {
+	ncap, ok := obj.(*crdapi.NodeCapability)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		ncap, ok = tombstone.Obj.(*crdapi.NodeCapability)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+	}
+	c.enqueueProvisioner(ncap.Spec.PluginInfo.Name)
+}
+
+// handleNodeObject enqueues the provisioners of the NodeCapabilities on a deleted node, so the
+// topology of the node is dropped before the NodeCapabilities are garbage collected.
+func (c *Controller) handleNodeObject(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		node, ok = tombstone.Obj.(*corev1.Node)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+	}
+	ncaps, err := c.ncapLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, ncap := range ncaps {
+		if ncap.Spec.NodeName == node.GetName() {
+			c.enqueueProvisioner(ncap.Spec.PluginInfo.Name)
+		}
+	}
+}
+
+// enqueueSnapClass enqueues all StorageClasses of the driver of the VolumeSnapshotClass.
+func (c *Controller) enqueueSnapClass(obj interface{}) {
+	snapClass, err := snapshot.ClassFromObject(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.enqueueProvisioner(snapClass.Driver)
+}
+
+// enqueueOverride enqueues all StorageClasses of the provisioner the CapabilityOverride applies to.
+func (c *Controller) enqueueOverride(obj interface{}) {
+	override, ok := obj.(*crdapi.CapabilityOverride)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type"))
+			return
+		}
+		override, ok = tombstone.Obj.(*crdapi.CapabilityOverride)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("error decoding object tombstone, invalid type"))
+			return
+		}
+	}
+	c.enqueueProvisioner(override.Spec.Provisioner)
+}
+
+// enqueueAllStorageClasses enqueues every StorageClass.
+func (c *Controller) enqueueAllStorageClasses() {
+	scList, err := c.scLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, v := range scList {
+		c.workqueue.Add(v.Name)
+	}
+}
+
+func (c *Controller) enqueueProvisioner(provisioner string) {
 	scList, err := c.scLister.List(labels.Everything())
 	if err != nil {
 		utilruntime.HandleError(err)
@@ -207,23 +405,32 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer c.workqueue.ShutDown()
 
-	isValid, err := c.IsValidKubernetesVersion()
-	if err != nil {
-		return err
-	}
-	if !isValid {
-		klog.V(0).Info("StorageCapability controller minimal kubernetes version %s, skipped.", MinimalKubernetesVersion)
-		return nil
-	}
 	// Start the informer factories to begin populating the informer caches
 	klog.Info("Starting StorageClassCapability controller")
 
 	// Wait for the caches to be synced before starting workers
 	klog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.scSynced, c.snapSynced, c.pcapSynced, c.sccapSynced); !ok {
+	synced := []cache.InformerSynced{c.scSynced, c.pcapSynced, c.sccapSynced, c.ncapSynced, c.overrideSynced, c.nodeSynced}
+	if c.snapSynced != nil {
+		synced = append(synced, c.snapSynced)
+	}
+	if ok := cache.WaitForCacheSync(stopCh, synced...); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
+	klog.Info("Discovering features supported by the cluster")
+	discovered := true
+	if err := c.syncCompatibility(); err != nil {
+		utilruntime.HandleError(fmt.Errorf("discover features supported by the cluster: %v", err))
+		discovered = false
+	}
+	go c.resyncCompatibility(discovered, stopCh)
+
+	klog.Info("Deleting orphaned StorageClassCapabilities")
+	if err := c.deleteOrphanedSccaps(); err != nil {
+		utilruntime.HandleError(fmt.Errorf("delete orphaned StorageClassCapabilities: %v", err))
+	}
+
 	klog.Info("Starting workers")
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runWorker, time.Second, stopCh)
@@ -259,7 +466,10 @@ func (c *Controller) processNextWorkItem() bool {
 			return nil
 		}
 
-		if err := c.syncHandler(key); err != nil {
+		start := time.Now()
+		err := c.syncHandler(key)
+		recordSync(key, err, start)
+		if err != nil {
 			c.workqueue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
@@ -293,8 +503,11 @@ func (c *Controller) syncHandler(key string) error {
 			utilruntime.HandleError(fmt.Errorf("storageclass '%s' in work queue no longer exists", key))
 			// If StorageClass does not exist, StorageClassCapability will be deleted.
 			klog.V(4).Infof("Delete StorageClassProvisioner %s", name)
+			// The garbage collector also removes it by the owner reference, so NotFound is expected.
 			err := c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Delete(name, &metav1.DeleteOptions{})
-			klog.V(4).Infof("Delete StorageClassProvisioner %s, err %s", name, err.Error())
+			if err != nil && !errors.IsNotFound(err) {
+				klog.V(4).Infof("Delete StorageClassProvisioner %s, err %s", name, err)
+			}
 			return nil
 		}
 		return err
@@ -315,54 +528,204 @@ func (c *Controller) syncHandler(key string) error {
 	if err != nil {
 		if errors.IsNotFound(err) {
 			klog.V(4).Infof("ProvisionerCapability %s not found", sc.Provisioner)
-			return nil
+			return c.syncProvisionerMissing(sc)
 		} else {
 			return err
 		}
 	}
-	// Get SnapshotClass
-	snapClass, err := c.snapLister.Get(sc.GetName())
+	recordLastProbe(pcap)
+	pcap, err = c.syncPcapTopology(pcap)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			klog.V(4).Infof("SnapshotClass %s not found", sc.GetName())
-		} else {
+		return err
+	}
+	// Get SnapshotClass
+	var snapClasses []*snapshot.VolumeSnapshotClass
+	snapLister := c.getSnapLister()
+	if snapLister != nil {
+		snapClasses, err = snapLister.List(labels.Everything())
+		if err != nil {
 			return err
 		}
 	}
+	snapClass := chooseSnapshotClass(sc, snapClasses)
+	snapClassNames := snapshotClassNames(sc, snapClasses)
+	if snapClass == nil {
+		klog.V(4).Infof("SnapshotClass of StorageClass %s not found", sc.GetName())
+	}
+	snapCond := newSnapshotCondition(snapLister != nil, sc, snapClass)
+	// Get CapabilityOverrides of the provisioner
+	overrides, err := c.listCapabilityOverrides(sc.Provisioner)
+	if err != nil {
+		return err
+	}
 	// Get exist StorageClassCapability
 	sccap, err := c.sccapLister.Get(sccapName)
 	if errors.IsNotFound(err) {
 		// If the resource doesn't exist, we'll create it
 		klog.V(4).Infof("Create StorageClassProvisioner %s", sc.GetName())
-		sccap, err = c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Create(newSccap(sc, snapClass, pcap))
-		return err
+		res := newSccap(sc, snapClass, snapClassNames, pcap, overrides)
+		c.getCompatibility().restrict(res)
+		sccap, err = c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Create(res)
+		if err != nil {
+			return err
+		}
+		return c.syncSccapStatus(sccap, sc, pcap, snapCond)
 	}
 	if err != nil {
 		return err
 	}
 	klog.V(4).Infof("Update StorageClassProvisioner %s", sc.GetName())
 	// If the resource exist, we can update it.
-	_, err = c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Update(updateSccap(sccap, sc, snapClass, pcap))
+	res := updateSccap(sccap, sc, snapClass, snapClassNames, pcap, overrides)
+	c.getCompatibility().restrict(res)
+	sccap, err = c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Update(res)
 	if err != nil {
 		return err
 	}
-	return nil
+	return c.syncSccapStatus(sccap, sc, pcap, snapCond)
 }
 
-func (c *Controller) IsValidKubernetesVersion() (bool, error) {
-	minVer := version.MustParseGeneric(MinimalKubernetesVersion)
-	rawVer, err := c.kubeclientset.Discovery().ServerVersion()
+// syncProvisionerMissing makes sure a StorageClassCapability exists for the StorageClass and
+// reports that no ProvisionerCapability has been found for its provisioner.
+func (c *Controller) syncProvisionerMissing(sc *v1.StorageClass) error {
+	sccap, err := c.sccapLister.Get(sc.GetName())
+	if errors.IsNotFound(err) {
+		klog.V(4).Infof("Create StorageClassProvisioner %s without ProvisionerCapability", sc.GetName())
+		res := &crdapi.StorageClassCapability{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: sc.GetName(),
+			},
+			Spec: crdapi.StorageClassCapabilitySpec{
+				Provisioner: sc.Provisioner,
+				Features: crdapi.StorageClassCapabilitySpecFeatures{
+					Volume: crdapi.ProvisionerCapabilitySpecFeaturesVolume{
+						Expand: crdapi.ExpandModeUnknown,
+					},
+				},
+			},
+		}
+		setSccapOwner(&res.ObjectMeta, sc, nil, "")
+		sccap, err = c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Create(res)
+	}
 	if err != nil {
-		return false, err
+		return err
+	}
+	status := newSccapStatus(sccap, nil, time.Now())
+	if status != nil {
+		status.UnsupportedFeatures = c.getCompatibility().unsupportedFeatures()
 	}
-	ver, err := version.ParseSemantic(rawVer.String())
+	return c.updateSccapStatus(sccap, status)
+}
+
+// syncPcapTopology aggregates the topology reported by NodeCapability into the status of ProvisionerCapability.
+func (c *Controller) syncPcapTopology(pcap *crdapi.ProvisionerCapability) (*crdapi.ProvisionerCapability, error) {
+	ncaps, err := c.ncapLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	nodeList, err := c.nodeLister.List(labels.Everything())
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	nodes := sets.NewString()
+	for _, node := range nodeList {
+		nodes.Insert(node.GetName())
+	}
+	topology := aggregateTopology(pcap.GetName(), ncaps, nodes, time.Now())
+	if reflect.DeepEqual(pcap.Status.Topology, topology) {
+		return pcap, nil
 	}
-	return ver.AtLeast(minVer), nil
+	klog.V(4).Infof("Update topology of ProvisionerCapability %s", pcap.GetName())
+	res := pcap.DeepCopy()
+	res.Status.Topology = topology
+	return c.crdclientset.StorageV1alpha1().ProvisionerCapabilities().UpdateStatus(res)
 }
 
-func newSccap(storageClass *v1.StorageClass, snapClass *snapapi.VolumeSnapshotClass, pcap *crdapi.ProvisionerCapability) *crdapi.StorageClassCapability {
+// syncSccapStatus computes status of StorageClassCapability, restricts the topology of the
+// provisioner to the AllowedTopologies of StorageClass and records whether snapshot is available.
+func (c *Controller) syncSccapStatus(sccap *crdapi.StorageClassCapability, sc *v1.StorageClass, pcap *crdapi.ProvisionerCapability, snapCond crdapi.CapabilityCondition) error {
+	status := newSccapStatus(sccap, pcap, time.Now())
+	if status != nil && pcap != nil {
+		status.Topology = filterTopology(pcap.Status.Topology, sc.AllowedTopologies)
+		snapCond.ObservedGeneration = sccap.GetGeneration()
+		status.SetCondition(snapCond)
+	}
+	if status != nil {
+		status.UnsupportedFeatures = c.getCompatibility().unsupportedFeatures()
+	}
+	return c.updateSccapStatus(sccap, status)
+}
+
+// updateSccapStatus writes status through the status subresource if it has been changed.
+func (c *Controller) updateSccapStatus(sccap *crdapi.StorageClassCapability, status *crdapi.CapabilityStatus) error {
+	if sccap == nil || status == nil || reflect.DeepEqual(sccap.Status, *status) {
+		return nil
+	}
+	res := sccap.DeepCopy()
+	res.Status = *status
+	_, err := c.crdclientset.StorageV1alpha1().StorageClassCapabilities().UpdateStatus(res)
+	return err
+}
+
+// syncCompatibility evaluates the compatibility matrix against the cluster and syncs all StorageClasses again
+// if the unsupported features have been changed.
+// resyncCompatibility discovers the features supported by the cluster every CompatibilityResyncPeriod. Until the
+// first discovery succeeds, it is retried with backoff.
+func (c *Controller) resyncCompatibility(discovered bool, stopCh <-chan struct{}) {
+	delay := compatibilityRetryDelay
+	for {
+		period := CompatibilityResyncPeriod
+		if !discovered {
+			period = delay
+			if delay *= 2; delay > CompatibilityResyncPeriod {
+				delay = CompatibilityResyncPeriod
+			}
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(period):
+		}
+		if err := c.syncCompatibility(); err != nil {
+			utilruntime.HandleError(fmt.Errorf("discover features supported by the cluster: %v", err))
+			continue
+		}
+		discovered = true
+	}
+}
+
+// syncCompatibility discovers the features supported by the cluster and enqueues all StorageClasses if they
+// changed. If discovery fails, the last discovered features are kept, or all features of the matrix are
+// disabled if none has been discovered yet.
+func (c *Controller) syncCompatibility() error {
+	compat, err := discoverCompatibility(c.kubeclientset.Discovery())
+	if err != nil {
+		if c.getCompatibility() != nil {
+			return err
+		}
+		compat = discoveryFailedCompatibility(err)
+	}
+	c.compatMutex.Lock()
+	changed := c.compat == nil || !c.compat.equal(compat)
+	c.compat = compat
+	c.compatMutex.Unlock()
+	if !changed {
+		return err
+	}
+	for _, f := range compat.unsupportedFeatures() {
+		klog.Warningf("Feature %s is disabled, %s: %s", f.Feature, f.Reason, f.Message)
+	}
+	c.enqueueAllStorageClasses()
+	return err
+}
+
+func (c *Controller) getCompatibility() *compatibility {
+	c.compatMutex.RLock()
+	defer c.compatMutex.RUnlock()
+	return c.compat
+}
+
+func newSccap(storageClass *v1.StorageClass, snapClass *snapshot.VolumeSnapshotClass, snapClassNames []string, pcap *crdapi.ProvisionerCapability, overrides []*crdapi.CapabilityOverride) *crdapi.StorageClassCapability {
 	if storageClass == nil || pcap == nil {
 		return nil
 	}
@@ -370,27 +733,14 @@ func newSccap(storageClass *v1.StorageClass, snapClass *snapapi.VolumeSnapshotCl
 		ObjectMeta: metav1.ObjectMeta{
 			Name: storageClass.GetName(),
 		},
-		Spec: crdapi.StorageClassCapabilitySpec{
-			Provisioner: storageClass.Provisioner,
-			Features: crdapi.StorageClassCapabilitySpecFeatures{
-				Topology: pcap.Spec.Features.Topology,
-				Volume:   pcap.Spec.Features.Volume,
-			},
-		},
-	}
-	// set volume features
-	if *storageClass.AllowVolumeExpansion != true {
-		res.Spec.Features.Volume.Expand = crdapi.ExpandModeUnknown
-	}
-	// set snapshot features
-	if snapClass != nil && snapClass.Driver == pcap.GetName() {
-		res.Spec.Features.Snapshot = pcap.Spec.Features.Snapshot
+		Spec: newSccapSpec(storageClass, snapClass, snapClassNames, pcap, overrides),
 	}
+	setSccapOwner(&res.ObjectMeta, storageClass, pcap, res.Spec.SnapshotClassName)
 	klog.V(4).Info("Create: ", res)
 	return res
 }
 
-func updateSccap(sccap *crdapi.StorageClassCapability, storageClass *v1.StorageClass, snapClass *snapapi.VolumeSnapshotClass, pcap *crdapi.ProvisionerCapability) *crdapi.StorageClassCapability {
+func updateSccap(sccap *crdapi.StorageClassCapability, storageClass *v1.StorageClass, snapClass *snapshot.VolumeSnapshotClass, snapClassNames []string, pcap *crdapi.ProvisionerCapability, overrides []*crdapi.CapabilityOverride) *crdapi.StorageClassCapability {
 	if sccap == nil || storageClass == nil || pcap == nil {
 		return nil
 	}
@@ -399,21 +749,131 @@ func updateSccap(sccap *crdapi.StorageClassCapability, storageClass *v1.StorageC
 		return nil
 	}
 	res := sccap.DeepCopy()
-	res.Spec = crdapi.StorageClassCapabilitySpec{
-		Provisioner: storageClass.Provisioner,
+	res.Spec = newSccapSpec(storageClass, snapClass, snapClassNames, pcap, overrides)
+	setSccapOwner(&res.ObjectMeta, storageClass, pcap, res.Spec.SnapshotClassName)
+	klog.V(4).Info("Update: ", res)
+	return res
+}
+
+// newSccapSpec derives the spec of StorageClassCapability from ProvisionerCapability. CapabilityOverrides are
+// applied to the provisioner features before they are restricted by StorageClass and VolumeSnapshotClass.
+// All VolumeSnapshotClasses of the provisioner are listed in snapClassNames, snapClass is the chosen one.
+func newSccapSpec(storageClass *v1.StorageClass, snapClass *snapshot.VolumeSnapshotClass, snapClassNames []string, pcap *crdapi.ProvisionerCapability, overrides []*crdapi.CapabilityOverride) crdapi.StorageClassCapabilitySpec {
+	spec := crdapi.StorageClassCapabilitySpec{
+		Provisioner:        storageClass.Provisioner,
+		SnapshotClassNames: snapClassNames,
 		Features: crdapi.StorageClassCapabilitySpecFeatures{
 			Topology: pcap.Spec.Features.Topology,
-			Volume:   pcap.Spec.Features.Volume,
+			Volume:   *pcap.Spec.Features.Volume.DeepCopy(),
+			Snapshot: pcap.Spec.Features.Snapshot,
+			Node:     pcap.Spec.Features.Node,
 		},
 	}
+	applyCapabilityOverrides(&spec.Features, storageClass.Parameters, overrides)
 	// set volume features
-	if *storageClass.AllowVolumeExpansion != true {
-		res.Spec.Features.Volume.Expand = crdapi.ExpandModeUnknown
+	if storageClass.AllowVolumeExpansion == nil || !*storageClass.AllowVolumeExpansion {
+		spec.Features.Volume.Expand = crdapi.ExpandModeUnknown
 	}
 	// set snapshot features
-	if snapClass != nil && snapClass.GetName() == storageClass.GetName() && snapClass.Driver == pcap.GetName() {
-		res.Spec.Features.Snapshot = pcap.Spec.Features.Snapshot
+	if snapClass == nil || snapClass.Driver != pcap.GetName() {
+		spec.Features.Snapshot = crdapi.ProvisionerCapabilitySpecFeaturesSnapshot{}
+	} else {
+		spec.SnapshotClassName = snapClass.GetName()
 	}
-	klog.V(4).Info("Update: ", res)
-	return res
+	return spec
+}
+
+// newSccapStatus computes the status of StorageClassCapability from ProvisionerCapability.
+// The pcap is nil if the provisioner of StorageClass has no ProvisionerCapability.
+func newSccapStatus(sccap *crdapi.StorageClassCapability, pcap *crdapi.ProvisionerCapability, now time.Time) *crdapi.CapabilityStatus {
+	if sccap == nil {
+		return nil
+	}
+	status := sccap.Status.DeepCopy()
+	gen := sccap.GetGeneration()
+	status.ObservedGeneration = gen
+	if pcap == nil {
+		status.LastProbeTime = nil
+		status.Topology = nil
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityProvisionerMissing,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "ProvisionerCapabilityNotFound",
+			Message:            fmt.Sprintf("ProvisionerCapability %s not found", sccap.Spec.Provisioner),
+		})
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "ProvisionerCapabilityNotFound",
+			Message:            fmt.Sprintf("ProvisionerCapability %s not found", sccap.Spec.Provisioner),
+		})
+		status.RemoveCondition(crdapi.CapabilityStale)
+		status.RemoveCondition(crdapi.CapabilityProbeFailed)
+		return status
+	}
+
+	status.LastProbeTime = pcap.Status.LastProbeTime.DeepCopy()
+	status.SetCondition(crdapi.CapabilityCondition{
+		Type:               crdapi.CapabilityProvisionerMissing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: gen,
+		Reason:             "ProvisionerCapabilityFound",
+	})
+	// Probe failure is reported by the sidecar.
+	probeFailed := pcap.Status.IsConditionTrue(crdapi.CapabilityProbeFailed)
+	if probeFailed {
+		cond := pcap.Status.FindCondition(crdapi.CapabilityProbeFailed)
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityProbeFailed,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             cond.Reason,
+			Message:            cond.Message,
+		})
+	} else {
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityProbeFailed,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "ProbeSucceeded",
+		})
+	}
+	// Capability data is stale if the sidecar has not probed the plugin for a while.
+	stale := pcap.Status.LastProbeTime == nil || now.Sub(pcap.Status.LastProbeTime.Time) > StaleThreshold
+	if stale {
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityStale,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "ProbeOutdated",
+			Message:            fmt.Sprintf("ProvisionerCapability %s has not been probed for more than %s", pcap.GetName(), StaleThreshold),
+		})
+	} else {
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityStale,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "ProbeUpToDate",
+		})
+	}
+	if probeFailed || stale {
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "ProvisionerCapabilityNotReady",
+			Message:            fmt.Sprintf("ProvisionerCapability %s is not ready", pcap.GetName()),
+		})
+	} else {
+		status.SetCondition(crdapi.CapabilityCondition{
+			Type:               crdapi.CapabilityReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "Synced",
+			Message:            MessageResourceSynced,
+		})
+	}
+	return status
 }
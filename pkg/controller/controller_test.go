@@ -19,17 +19,23 @@
 package controller
 
 import (
-	snapbeta1 "github.com/kubernetes-csi/external-snapshotter/v2/pkg/apis/volumesnapshot/v1beta1"
-	snapfake "github.com/kubernetes-csi/external-snapshotter/v2/pkg/client/clientset/versioned/fake"
-	snapinformers "github.com/kubernetes-csi/external-snapshotter/v2/pkg/client/informers/externalversions"
 	crdv1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
 	crdfake "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/fake"
 	crdinformers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	kubeinformers "k8s.io/client-go/informers"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
@@ -49,12 +55,18 @@ type fixture struct {
 
 	crdclient  *crdfake.Clientset
 	kubeclient *k8sfake.Clientset
-	snapclient *snapfake.Clientset
+	snapclient *dynamicfake.FakeDynamicClient
 
 	sccapLister []*crdv1alpha1.StorageClassCapability
 	pcapLister  []*crdv1alpha1.ProvisionerCapability
 	scLister    []*storagev1.StorageClass
-	snapLister  []*snapbeta1.VolumeSnapshotClass
+	snapLister  []*snapshot.VolumeSnapshotClass
+	ncapLister  []*crdv1alpha1.NodeCapability
+
+	overrideLister []*crdv1alpha1.CapabilityOverride
+
+	// snapVersion is the served version of snapshot.storage.k8s.io.
+	snapVersion string
 
 	kubeactions []core.Action
 	crdaction   []core.Action
@@ -71,6 +83,7 @@ func newFixture(t *testing.T) *fixture {
 	f.kubeobject = []runtime.Object{}
 	f.crdobject = []runtime.Object{}
 	f.snapobject = []runtime.Object{}
+	f.snapVersion = snapshot.V1beta1
 	return f
 }
 
@@ -114,8 +127,8 @@ func newStorageClass(name string, provisioner string) *storagev1.StorageClass {
 	}
 }
 
-func newSnapshotClass(name string, provisioner string) *snapbeta1.VolumeSnapshotClass {
-	return &snapbeta1.VolumeSnapshotClass{
+func newSnapshotClass(name string, provisioner string) *snapshot.VolumeSnapshotClass {
+	return &snapshot.VolumeSnapshotClass{
 		ObjectMeta: v1.ObjectMeta{
 			Name: name,
 		},
@@ -124,30 +137,46 @@ func newSnapshotClass(name string, provisioner string) *snapbeta1.VolumeSnapshot
 }
 
 func (f *fixture) newController() (*Controller, kubeinformers.SharedInformerFactory,
-	crdinformers.SharedInformerFactory, snapinformers.SharedInformerFactory) {
+	crdinformers.SharedInformerFactory, dynamicinformer.DynamicSharedInformerFactory) {
 	f.kubeclient = k8sfake.NewSimpleClientset(f.kubeobject...)
 	f.crdclient = crdfake.NewSimpleClientset(f.crdobject...)
-	f.snapclient = snapfake.NewSimpleClientset(f.snapobject...)
+	f.snapclient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), f.snapobject...)
 
 	k8sI := kubeinformers.NewSharedInformerFactory(f.kubeclient, noResyncPeriodFunc())
 	crdI := crdinformers.NewSharedInformerFactory(f.crdclient, noResyncPeriodFunc())
-	snapI := snapinformers.NewSharedInformerFactory(f.snapclient, noResyncPeriodFunc())
+	snapI := dynamicinformer.NewDynamicSharedInformerFactory(f.snapclient, noResyncPeriodFunc())
+	// The snapshot API is not served if snapVersion is empty.
+	var snapInformer snapshot.ClassInformer
+	if f.snapVersion != "" {
+		snapInformer = snapshot.NewClassInformer(snapI, f.snapVersion)
+	}
 
 	c := NewController(f.kubeclient, f.crdclient,
 		k8sI.Storage().V1().StorageClasses(),
-		snapI.Snapshot().V1beta1().VolumeSnapshotClasses(),
-		crdI.Storage().V1alpha1().ProvisionerCapabilities(), crdI.Storage().V1alpha1().StorageClassCapabilities())
+		snapInformer,
+		crdI.Storage().V1alpha1().ProvisionerCapabilities(), crdI.Storage().V1alpha1().StorageClassCapabilities(),
+		crdI.Storage().V1alpha1().NodeCapabilities(), crdI.Storage().V1alpha1().CapabilityOverrides(),
+		k8sI.Core().V1().Nodes())
 
 	c.sccapSynced = alwaysReady
-	c.snapSynced = alwaysReady
+	if snapInformer != nil {
+		c.snapSynced = alwaysReady
+	}
 	c.pcapSynced = alwaysReady
 	c.sccapSynced = alwaysReady
+	c.ncapSynced = alwaysReady
+	c.overrideSynced = alwaysReady
+	c.nodeSynced = alwaysReady
 
 	for _, sc := range f.scLister {
 		k8sI.Storage().V1().StorageClasses().Informer().GetIndexer().Add(sc)
 	}
 	for _, snap := range f.snapLister {
-		snapI.Snapshot().V1beta1().VolumeSnapshotClasses().Informer().GetIndexer().Add(snap)
+		obj, err := snap.ToUnstructured(f.snapVersion)
+		if err != nil {
+			f.t.Fatalf("Unexpected error converting snapshot class %s: %v", snap.Name, err)
+		}
+		snapInformer.Informer().GetIndexer().Add(obj)
 	}
 	for _, pcap := range f.pcapLister {
 		crdI.Storage().V1alpha1().ProvisionerCapabilities().Informer().GetIndexer().Add(pcap)
@@ -155,6 +184,12 @@ func (f *fixture) newController() (*Controller, kubeinformers.SharedInformerFact
 	for _, sccap := range f.sccapLister {
 		crdI.Storage().V1alpha1().StorageClassCapabilities().Informer().GetIndexer().Add(sccap)
 	}
+	for _, ncap := range f.ncapLister {
+		crdI.Storage().V1alpha1().NodeCapabilities().Informer().GetIndexer().Add(ncap)
+	}
+	for _, override := range f.overrideLister {
+		crdI.Storage().V1alpha1().CapabilityOverrides().Informer().GetIndexer().Add(override)
+	}
 	return c, k8sI, crdI, snapI
 }
 
@@ -277,6 +312,284 @@ func TestCreateStorageClass(t *testing.T) {
 	f.run(getKey(sc, t))
 }
 
+func TestCreateStorageClassWithSnapshotClass(t *testing.T) {
+	for _, version := range snapshot.SupportedVersions {
+		f := newFixture(t)
+		f.snapVersion = version
+		sc := newStorageClass("sc-example", "csi.example.com")
+		pcap := newProvisionerCapability("csi.example.com")
+		snapClass := newSnapshotClass("snap-example", "csi.example.com")
+
+		f.scLister = append(f.scLister, sc)
+		f.kubeobject = append(f.kubeobject, sc)
+		f.pcapLister = append(f.pcapLister, pcap)
+		f.crdobject = append(f.crdobject, pcap)
+		f.snapLister = append(f.snapLister, snapClass)
+
+		f.run(getKey(sc, t))
+
+		sccap, err := f.crdclient.StorageV1alpha1().StorageClassCapabilities().Get(sc.Name, v1.GetOptions{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error getting StorageClassCapability: %v", version, err)
+		}
+		if sccap.Spec.SnapshotClassName != snapClass.Name {
+			t.Errorf("%s: expected snapshot class %q, got %q", version, snapClass.Name, sccap.Spec.SnapshotClassName)
+		}
+		if !sccap.Spec.Features.Snapshot.Create {
+			t.Errorf("%s: expected snapshot create to be enabled", version)
+		}
+	}
+}
+
+func TestSetSccapOwner(t *testing.T) {
+	sc := newStorageClass("sc-example", "csi.example.com")
+	sc.UID = "sc-uid"
+	pcap := newProvisionerCapability("csi.example.com")
+	snapClass := newSnapshotClass("snap-example", "csi.example.com")
+
+	sccap := newSccap(sc, snapClass, nil, pcap, nil)
+	expectedRef := newStorageClassOwnerReference(sc)
+	if len(sccap.OwnerReferences) != 1 || !reflect.DeepEqual(sccap.OwnerReferences[0], expectedRef) {
+		t.Errorf("expected owner references [%v], got %v", expectedRef, sccap.OwnerReferences)
+	}
+	expectedLabels := map[string]string{
+		LabelProvisionerCapability: "csi.example.com",
+		LabelSnapshotClass:         "snap-example",
+	}
+	if !reflect.DeepEqual(sccap.Labels, expectedLabels) {
+		t.Errorf("expected labels %v, got %v", expectedLabels, sccap.Labels)
+	}
+
+	// A recreated StorageClass replaces the owner reference, and the snapshot class label is removed with the class.
+	sc.UID = "sc-uid-recreated"
+	sccap = updateSccap(sccap, sc, nil, nil, pcap, nil)
+	expectedRef = newStorageClassOwnerReference(sc)
+	if len(sccap.OwnerReferences) != 1 || !reflect.DeepEqual(sccap.OwnerReferences[0], expectedRef) {
+		t.Errorf("expected owner references [%v], got %v", expectedRef, sccap.OwnerReferences)
+	}
+	if _, ok := sccap.Labels[LabelSnapshotClass]; ok {
+		t.Errorf("expected label %s to be removed, got %v", LabelSnapshotClass, sccap.Labels)
+	}
+}
+
+func TestDeleteOrphanedSccaps(t *testing.T) {
+	f := newFixture(t)
+	sc := newStorageClass("sc-example", "csi.example.com")
+	pcap := newProvisionerCapability("csi.example.com")
+	sccap := newSccap(sc, nil, nil, pcap, nil)
+	orphan := newSccap(newStorageClass("sc-deleted", "csi.example.com"), nil, nil, pcap, nil)
+
+	f.scLister = append(f.scLister, sc)
+	f.kubeobject = append(f.kubeobject, sc)
+	f.sccapLister = append(f.sccapLister, sccap, orphan)
+	f.crdobject = append(f.crdobject, sccap, orphan)
+
+	c, _, _, _ := f.newController()
+	if err := c.deleteOrphanedSccaps(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.crdclient.StorageV1alpha1().StorageClassCapabilities().Get(sccap.Name, v1.GetOptions{}); err != nil {
+		t.Errorf("expected StorageClassCapability %s to be kept, got %v", sccap.Name, err)
+	}
+	if _, err := f.crdclient.StorageV1alpha1().StorageClassCapabilities().Get(orphan.Name, v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected StorageClassCapability %s to be deleted, got %v", orphan.Name, err)
+	}
+}
+
+func TestSnapshotAPINotServed(t *testing.T) {
+	f := newFixture(t)
+	f.snapVersion = ""
+	sc := newStorageClass("sc-example", "csi.example.com")
+	pcap := newProvisionerCapability("csi.example.com")
+
+	f.scLister = append(f.scLister, sc)
+	f.kubeobject = append(f.kubeobject, sc)
+	f.pcapLister = append(f.pcapLister, pcap)
+	f.crdobject = append(f.crdobject, pcap)
+
+	c, _, crdI, _ := f.newController()
+	if err := c.syncHandler(getKey(sc, t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sccap, err := f.crdclient.StorageV1alpha1().StorageClassCapabilities().Get(sc.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error getting StorageClassCapability: %v", err)
+	}
+	if sccap.Spec.Features.Snapshot.Create {
+		t.Errorf("expected snapshot create to be disabled")
+	}
+	cond := sccap.Status.FindCondition(crdv1alpha1.CapabilitySnapshotUnavailable)
+	if cond == nil || cond.Status != v1.ConditionTrue || cond.Reason != "SnapshotAPINotServed" {
+		t.Errorf("expected condition %s with reason SnapshotAPINotServed, got %v", crdv1alpha1.CapabilitySnapshotUnavailable, cond)
+	}
+
+	crdI.Storage().V1alpha1().StorageClassCapabilities().Informer().GetIndexer().Add(sccap)
+
+	// The snapshot API is served later.
+	snapClass, err := newSnapshotClass("snap-example", "csi.example.com").ToUnstructured(snapshot.V1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	snapI := dynamicinformer.NewDynamicSharedInformerFactory(dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), snapClass), noResyncPeriodFunc())
+	c.WatchSnapshotClasses(snapshot.NewClassInformer(snapI, snapshot.V1), stopCh)
+	snapI.Start(stopCh)
+	err = wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return c.getSnapLister() != nil && c.workqueue.Len() > 0, nil
+	})
+	if err != nil {
+		t.Fatalf("expected VolumeSnapshotClasses to be watched and StorageClasses to be enqueued: %v", err)
+	}
+	if err := c.syncHandler(getKey(sc, t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sccap, err = f.crdclient.StorageV1alpha1().StorageClassCapabilities().Get(sc.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error getting StorageClassCapability: %v", err)
+	}
+	if sccap.Spec.SnapshotClassName != "snap-example" {
+		t.Errorf("expected snapshot class snap-example, got %q", sccap.Spec.SnapshotClassName)
+	}
+	if sccap.Status.IsConditionTrue(crdv1alpha1.CapabilitySnapshotUnavailable) {
+		t.Errorf("expected condition %s to be false", crdv1alpha1.CapabilitySnapshotUnavailable)
+	}
+}
+
+func TestDiscoverCompatibility(t *testing.T) {
+	snapshotResources := &v1.APIResourceList{
+		GroupVersion: "snapshot.storage.k8s.io/v1",
+		APIResources: []v1.APIResource{{Name: "volumesnapshotclasses"}},
+	}
+	capacityResources := &v1.APIResourceList{
+		GroupVersion: "storage.k8s.io/v1beta1",
+		APIResources: []v1.APIResource{{Name: "csistoragecapacities"}},
+	}
+	tests := []struct {
+		name      string
+		version   string
+		resources []*v1.APIResourceList
+		expected  map[string]string
+	}{
+		{
+			name:     "v1.15 without APIs",
+			version:  "v1.15.12",
+			expected: map[string]string{"snapshot": ReasonKubernetesVersionTooOld, "volume.expandMode": ReasonKubernetesVersionTooOld, "volume.clone": ReasonKubernetesVersionTooOld, "volume.capacity": ReasonAPINotServed},
+		},
+		{
+			name:     "v1.17 without snapshot CRDs",
+			version:  "v1.17.4",
+			expected: map[string]string{"snapshot": ReasonAPINotServed, "volume.capacity": ReasonAPINotServed},
+		},
+		{
+			name:      "v1.21 with all APIs",
+			version:   "v1.21.1+k3s1",
+			resources: []*v1.APIResourceList{snapshotResources, capacityResources},
+			expected:  map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		client := k8sfake.NewSimpleClientset()
+		discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+		discovery.FakedServerVersion = &version.Info{GitVersion: test.version}
+		discovery.Resources = test.resources
+		compat, err := discoverCompatibility(discovery)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		got := map[string]string{}
+		for _, f := range compat.unsupportedFeatures() {
+			got[f.Feature] = f.Reason
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected unsupported features %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSyncCompatibilityDiscoveryFailed(t *testing.T) {
+	f := newFixture(t)
+	c, _, _, _ := f.newController()
+	discovery := c.kubeclientset.Discovery().(*fakediscovery.FakeDiscovery)
+	features := func() map[string]string {
+		got := map[string]string{}
+		for _, f := range c.getCompatibility().unsupportedFeatures() {
+			got[f.Feature] = f.Reason
+		}
+		return got
+	}
+
+	// Every feature of the matrix is disabled until the cluster is discovered.
+	discovery.FakedServerVersion = &version.Info{GitVersion: "invalid"}
+	if err := c.syncCompatibility(); err == nil {
+		t.Fatal("expected discovery error")
+	}
+	expected := map[string]string{"snapshot": ReasonDiscoveryFailed, "volume.expandMode": ReasonDiscoveryFailed,
+		"volume.clone": ReasonDiscoveryFailed, "volume.capacity": ReasonDiscoveryFailed}
+	if got := features(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected unsupported features %v, got %v", expected, got)
+	}
+
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.17.4"}
+	if err := c.syncCompatibility(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = map[string]string{"snapshot": ReasonAPINotServed, "volume.capacity": ReasonAPINotServed}
+	if got := features(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected unsupported features %v, got %v", expected, got)
+	}
+
+	// A failed discovery keeps the features discovered before.
+	discovery.FakedServerVersion = &version.Info{GitVersion: "invalid"}
+	if err := c.syncCompatibility(); err == nil {
+		t.Fatal("expected discovery error")
+	}
+	if got := features(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected unsupported features %v, got %v", expected, got)
+	}
+}
+
+func TestSyncUnsupportedFeatures(t *testing.T) {
+	f := newFixture(t)
+	sc := newStorageClass("sc-example", "csi.example.com")
+	allowExpansion := true
+	sc.AllowVolumeExpansion = &allowExpansion
+	pcap := newProvisionerCapability("csi.example.com")
+	snapClass := newSnapshotClass("snap-example", "csi.example.com")
+
+	f.scLister = append(f.scLister, sc)
+	f.kubeobject = append(f.kubeobject, sc)
+	f.pcapLister = append(f.pcapLister, pcap)
+	f.crdobject = append(f.crdobject, pcap)
+	f.snapLister = append(f.snapLister, snapClass)
+
+	c, _, _, _ := f.newController()
+	c.compat = &compatibility{serverVersion: "1.15.12", unsupported: []crdv1alpha1.UnsupportedFeature{
+		{Feature: "volume.expandMode", Reason: ReasonKubernetesVersionTooOld},
+		{Feature: "snapshot", Reason: ReasonKubernetesVersionTooOld},
+	}}
+	if err := c.syncHandler(getKey(sc, t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sccap, err := f.crdclient.StorageV1alpha1().StorageClassCapabilities().Get(sc.Name, v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error getting StorageClassCapability: %v", err)
+	}
+	if sccap.Spec.Features.Volume.Expand != crdv1alpha1.ExpandModeUnknown {
+		t.Errorf("expected expand mode %s, got %s", crdv1alpha1.ExpandModeUnknown, sccap.Spec.Features.Volume.Expand)
+	}
+	if sccap.Spec.Features.Snapshot.Create {
+		t.Errorf("expected snapshot create to be disabled")
+	}
+	if !sccap.Spec.Features.Volume.Clone {
+		t.Errorf("expected clone to be enabled")
+	}
+	if !reflect.DeepEqual(sccap.Status.UnsupportedFeatures, c.compat.unsupported) {
+		t.Errorf("expected unsupported features %v, got %v", c.compat.unsupported, sccap.Status.UnsupportedFeatures)
+	}
+}
+
 func getKey(sc *storagev1.StorageClass, t *testing.T) string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(sc)
 	if err != nil {
@@ -285,3 +598,508 @@ func getKey(sc *storagev1.StorageClass, t *testing.T) string {
 	}
 	return key
 }
+
+func TestNewSccapStatus(t *testing.T) {
+	now := time.Now()
+	fresh := v1.NewTime(now.Add(-time.Minute))
+	outdated := v1.NewTime(now.Add(-2 * StaleThreshold))
+	tests := []struct {
+		name        string
+		pcap        *crdv1alpha1.ProvisionerCapability
+		expectTrue  []crdv1alpha1.CapabilityConditionType
+		expectFalse []crdv1alpha1.CapabilityConditionType
+	}{
+		{
+			name:        "provisioner missing",
+			pcap:        nil,
+			expectTrue:  []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityProvisionerMissing},
+			expectFalse: []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityReady},
+		},
+		{
+			name: "fresh",
+			pcap: func() *crdv1alpha1.ProvisionerCapability {
+				pcap := newProvisionerCapability("csi.example.com")
+				pcap.Status.LastProbeTime = &fresh
+				return pcap
+			}(),
+			expectTrue: []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityReady},
+			expectFalse: []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityStale,
+				crdv1alpha1.CapabilityProbeFailed, crdv1alpha1.CapabilityProvisionerMissing},
+		},
+		{
+			name: "stale",
+			pcap: func() *crdv1alpha1.ProvisionerCapability {
+				pcap := newProvisionerCapability("csi.example.com")
+				pcap.Status.LastProbeTime = &outdated
+				return pcap
+			}(),
+			expectTrue:  []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityStale},
+			expectFalse: []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityReady},
+		},
+		{
+			name: "probe failed",
+			pcap: func() *crdv1alpha1.ProvisionerCapability {
+				pcap := newProvisionerCapability("csi.example.com")
+				pcap.Status.LastProbeTime = &fresh
+				pcap.Status.SetCondition(crdv1alpha1.CapabilityCondition{
+					Type:   crdv1alpha1.CapabilityProbeFailed,
+					Status: v1.ConditionTrue,
+				})
+				return pcap
+			}(),
+			expectTrue:  []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityProbeFailed},
+			expectFalse: []crdv1alpha1.CapabilityConditionType{crdv1alpha1.CapabilityReady, crdv1alpha1.CapabilityStale},
+		},
+	}
+	for _, test := range tests {
+		sccap := &crdv1alpha1.StorageClassCapability{
+			ObjectMeta: v1.ObjectMeta{Name: "sc-example"},
+			Spec:       crdv1alpha1.StorageClassCapabilitySpec{Provisioner: "csi.example.com"},
+		}
+		status := newSccapStatus(sccap, test.pcap, now)
+		for _, condType := range test.expectTrue {
+			if !status.IsConditionTrue(condType) {
+				t.Errorf("%s: expect condition %s to be true, got %+v", test.name, condType, status.Conditions)
+			}
+		}
+		for _, condType := range test.expectFalse {
+			if status.FindCondition(condType) == nil || status.IsConditionTrue(condType) {
+				t.Errorf("%s: expect condition %s to be false, got %+v", test.name, condType, status.Conditions)
+			}
+		}
+	}
+}
+
+// newNodeCapability returns a NodeCapability probed successfully just now.
+func newNodeCapability(node string, provisioner string, topology map[string]string) *crdv1alpha1.NodeCapability {
+	now := v1.Now()
+	return &crdv1alpha1.NodeCapability{
+		ObjectMeta: v1.ObjectMeta{
+			Name: node + "." + provisioner,
+		},
+		Spec: crdv1alpha1.NodeCapabilitySpec{
+			NodeName: node,
+			PluginInfo: crdv1alpha1.ProvisionerCapabilitySpecPluginInfo{
+				Name:    provisioner,
+				Version: "v0.1.0",
+			},
+			NodeID:             node,
+			AccessibleTopology: topology,
+		},
+		Status: crdv1alpha1.CapabilityStatus{
+			LastProbeTime: &now,
+			Conditions: []crdv1alpha1.CapabilityCondition{
+				{Type: crdv1alpha1.CapabilityReady, Status: v1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func TestAggregateTopology(t *testing.T) {
+	zoneKey := "topology.example.com/zone"
+	ncaps := []*crdv1alpha1.NodeCapability{
+		newNodeCapability("node1", "csi.example.com", map[string]string{zoneKey: "zone-b"}),
+		newNodeCapability("node2", "csi.example.com", map[string]string{zoneKey: "zone-a"}),
+		newNodeCapability("node3", "csi.example.com", map[string]string{zoneKey: "zone-b"}),
+		newNodeCapability("node4", "csi.example.com", nil),
+		newNodeCapability("node1", "csi.other.com", map[string]string{"topology.other.com/rack": "rack-1"}),
+	}
+	expected := &crdv1alpha1.TopologyStatus{
+		Keys:     []string{zoneKey},
+		Segments: []map[string]string{{zoneKey: "zone-a"}, {zoneKey: "zone-b"}},
+	}
+	nodes := sets.NewString("node1", "node2", "node3", "node4")
+	if res := aggregateTopology("csi.example.com", ncaps, nodes, time.Now()); !reflect.DeepEqual(expected, res) {
+		t.Errorf("Wrong topology\nDiff:\n %s", diff.ObjectGoPrintSideBySide(expected, res))
+	}
+	if res := aggregateTopology("csi.none.com", ncaps, nodes, time.Now()); res != nil {
+		t.Errorf("Expect nil topology, got %+v", res)
+	}
+}
+
+func TestAggregateTopologySkipNotReadyNodes(t *testing.T) {
+	zoneKey := "topology.example.com/zone"
+	now := time.Now()
+	tests := []struct {
+		name     string
+		modify   func(ncap *crdv1alpha1.NodeCapability)
+		nodes    sets.String
+		expected *crdv1alpha1.TopologyStatus
+	}{
+		{
+			name:  "ready",
+			nodes: sets.NewString("node1", "node2"),
+			expected: &crdv1alpha1.TopologyStatus{
+				Keys:     []string{zoneKey},
+				Segments: []map[string]string{{zoneKey: "zone-a"}, {zoneKey: "zone-b"}},
+			},
+		},
+		{
+			name:  "node deleted",
+			nodes: sets.NewString("node1"),
+		},
+		{
+			name:  "not ready",
+			nodes: sets.NewString("node1", "node2"),
+			modify: func(ncap *crdv1alpha1.NodeCapability) {
+				ncap.Status.SetCondition(crdv1alpha1.CapabilityCondition{Type: crdv1alpha1.CapabilityReady, Status: v1.ConditionFalse})
+			},
+		},
+		{
+			name:  "no ready condition",
+			nodes: sets.NewString("node1", "node2"),
+			modify: func(ncap *crdv1alpha1.NodeCapability) {
+				ncap.Status.RemoveCondition(crdv1alpha1.CapabilityReady)
+			},
+		},
+		{
+			name:  "stale condition",
+			nodes: sets.NewString("node1", "node2"),
+			modify: func(ncap *crdv1alpha1.NodeCapability) {
+				ncap.Status.SetCondition(crdv1alpha1.CapabilityCondition{Type: crdv1alpha1.CapabilityStale, Status: v1.ConditionTrue})
+			},
+		},
+		{
+			name:  "probe outdated",
+			nodes: sets.NewString("node1", "node2"),
+			modify: func(ncap *crdv1alpha1.NodeCapability) {
+				outdated := v1.NewTime(now.Add(-StaleThreshold - time.Minute))
+				ncap.Status.LastProbeTime = &outdated
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node2 := newNodeCapability("node2", "csi.example.com", map[string]string{zoneKey: "zone-b"})
+			if test.modify != nil {
+				test.modify(node2)
+			}
+			ncaps := []*crdv1alpha1.NodeCapability{
+				newNodeCapability("node1", "csi.example.com", map[string]string{zoneKey: "zone-a"}),
+				node2,
+			}
+			expected := test.expected
+			if expected == nil {
+				expected = &crdv1alpha1.TopologyStatus{
+					Keys:     []string{zoneKey},
+					Segments: []map[string]string{{zoneKey: "zone-a"}},
+				}
+			}
+			if res := aggregateTopology("csi.example.com", ncaps, test.nodes, now); !reflect.DeepEqual(expected, res) {
+				t.Errorf("Wrong topology\nDiff:\n %s", diff.ObjectGoPrintSideBySide(expected, res))
+			}
+		})
+	}
+}
+
+func TestFilterTopology(t *testing.T) {
+	zoneKey := "topology.example.com/zone"
+	topology := &crdv1alpha1.TopologyStatus{
+		Keys:     []string{zoneKey},
+		Segments: []map[string]string{{zoneKey: "zone-a"}, {zoneKey: "zone-b"}, {zoneKey: "zone-c"}},
+	}
+	tests := []struct {
+		name     string
+		allowed  []corev1.TopologySelectorTerm
+		expected *crdv1alpha1.TopologyStatus
+	}{
+		{
+			name:     "no allowed topologies",
+			allowed:  nil,
+			expected: topology,
+		},
+		{
+			name: "single term",
+			allowed: []corev1.TopologySelectorTerm{{
+				MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+					{Key: zoneKey, Values: []string{"zone-a", "zone-c", "zone-d"}},
+				},
+			}},
+			expected: &crdv1alpha1.TopologyStatus{
+				Keys:     []string{zoneKey},
+				Segments: []map[string]string{{zoneKey: "zone-a"}, {zoneKey: "zone-c"}},
+			},
+		},
+		{
+			name: "multiple terms",
+			allowed: []corev1.TopologySelectorTerm{
+				{MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{{Key: zoneKey, Values: []string{"zone-b"}}}},
+				{MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{{Key: zoneKey, Values: []string{"zone-c"}}}},
+			},
+			expected: &crdv1alpha1.TopologyStatus{
+				Keys:     []string{zoneKey},
+				Segments: []map[string]string{{zoneKey: "zone-b"}, {zoneKey: "zone-c"}},
+			},
+		},
+		{
+			name: "unknown key",
+			allowed: []corev1.TopologySelectorTerm{{
+				MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+					{Key: "topology.example.com/region", Values: []string{"region-a"}},
+				},
+			}},
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		if res := filterTopology(topology, test.allowed); !reflect.DeepEqual(test.expected, res) {
+			t.Errorf("%s: wrong topology\nDiff:\n %s", test.name, diff.ObjectGoPrintSideBySide(test.expected, res))
+		}
+	}
+}
+
+func newCapabilityOverride(name string, provisioner string, rules ...crdv1alpha1.CapabilityOverrideRule) *crdv1alpha1.CapabilityOverride {
+	return &crdv1alpha1.CapabilityOverride{
+		ObjectMeta: v1.ObjectMeta{
+			Name: name,
+		},
+		Spec: crdv1alpha1.CapabilityOverrideSpec{
+			Provisioner: provisioner,
+			Rules:       rules,
+		},
+	}
+}
+
+func TestNewSccapWithOverrides(t *testing.T) {
+	disabled, enabled := false, true
+	expandOnline := crdv1alpha1.ExpandModeOnline
+	noSnapshotForLocal := crdv1alpha1.CapabilityOverrideRule{
+		Parameters: []crdv1alpha1.ParameterMatcher{
+			{Key: "type", Operator: crdv1alpha1.ParameterMatchIn, Values: []string{"local", "tmp"}},
+		},
+		Features: crdv1alpha1.FeaturesOverride{
+			Snapshot: &crdv1alpha1.SnapshotFeaturesOverride{Create: &disabled},
+			Volume:   &crdv1alpha1.VolumeFeaturesOverride{Clone: &disabled},
+		},
+	}
+	noCloneForXfs := crdv1alpha1.CapabilityOverrideRule{
+		Parameters: []crdv1alpha1.ParameterMatcher{
+			{Key: "fsType", Operator: crdv1alpha1.ParameterMatchNotIn, Values: []string{"ext4"}},
+			{Key: "fsType", Operator: crdv1alpha1.ParameterMatchExists},
+		},
+		Features: crdv1alpha1.FeaturesOverride{
+			Volume: &crdv1alpha1.VolumeFeaturesOverride{Clone: &disabled, Expand: &expandOnline},
+		},
+	}
+	enableList := crdv1alpha1.CapabilityOverrideRule{
+		Parameters: []crdv1alpha1.ParameterMatcher{
+			{Key: "legacy", Operator: crdv1alpha1.ParameterMatchDoesNotExist},
+		},
+		Features: crdv1alpha1.FeaturesOverride{
+			Volume: &crdv1alpha1.VolumeFeaturesOverride{List: &enabled},
+		},
+	}
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		overrides  []*crdv1alpha1.CapabilityOverride
+		expect     func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures)
+	}{
+		{
+			name:       "no overrides",
+			parameters: map[string]string{"type": "local"},
+			expect:     func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {},
+		},
+		{
+			name:       "parameter in values",
+			parameters: map[string]string{"type": "local"},
+			overrides:  []*crdv1alpha1.CapabilityOverride{newCapabilityOverride("local", "csi.example.com", noSnapshotForLocal)},
+			expect: func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {
+				features.Snapshot.Create = false
+				features.Volume.Clone = false
+			},
+		},
+		{
+			name:       "parameter not in values",
+			parameters: map[string]string{"type": "ssd"},
+			overrides:  []*crdv1alpha1.CapabilityOverride{newCapabilityOverride("local", "csi.example.com", noSnapshotForLocal)},
+			expect:     func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {},
+		},
+		{
+			name:       "all matchers of a rule must match",
+			parameters: map[string]string{"type": "ssd"},
+			overrides:  []*crdv1alpha1.CapabilityOverride{newCapabilityOverride("xfs", "csi.example.com", noCloneForXfs)},
+			expect:     func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {},
+		},
+		{
+			name:       "volume features overridden",
+			parameters: map[string]string{"fsType": "xfs"},
+			overrides:  []*crdv1alpha1.CapabilityOverride{newCapabilityOverride("xfs", "csi.example.com", noCloneForXfs)},
+			expect: func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {
+				features.Volume.Clone = false
+				features.Volume.Expand = crdv1alpha1.ExpandModeOnline
+			},
+		},
+		{
+			name:       "later rule takes precedence",
+			parameters: map[string]string{"type": "local"},
+			overrides: []*crdv1alpha1.CapabilityOverride{
+				newCapabilityOverride("a", "csi.example.com", noSnapshotForLocal, enableList),
+				newCapabilityOverride("b", "csi.example.com", crdv1alpha1.CapabilityOverrideRule{
+					Features: crdv1alpha1.FeaturesOverride{
+						Volume: &crdv1alpha1.VolumeFeaturesOverride{Clone: &enabled},
+					},
+				}),
+			},
+			expect: func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {
+				features.Snapshot.Create = false
+				features.Volume.List = true
+			},
+		},
+		{
+			name:       "controller and node features overridden",
+			parameters: map[string]string{"type": "local"},
+			overrides: []*crdv1alpha1.CapabilityOverride{newCapabilityOverride("local", "csi.example.com", crdv1alpha1.CapabilityOverrideRule{
+				Features: crdv1alpha1.FeaturesOverride{
+					Volume: &crdv1alpha1.VolumeFeaturesOverride{
+						Capacity:              &enabled,
+						ReadOnlyAttach:        &enabled,
+						ControllerExpand:      &enabled,
+						ListPublishedNodes:    &enabled,
+						Condition:             &enabled,
+						Get:                   &enabled,
+						SingleNodeMultiWriter: &enabled,
+						VolumeModes: []crdv1alpha1.VolumeModeCapability{
+							{VolumeMode: corev1.PersistentVolumeBlock, AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+						},
+					},
+					Node: &crdv1alpha1.NodeFeaturesOverride{
+						StageUnstage:          &enabled,
+						Expand:                &enabled,
+						Condition:             &enabled,
+						SingleNodeMultiWriter: &enabled,
+					},
+				},
+			})},
+			expect: func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {
+				features.Volume.Capacity = true
+				features.Volume.ReadOnlyAttach = true
+				features.Volume.ControllerExpand = true
+				features.Volume.ListPublishedNodes = true
+				features.Volume.Condition = true
+				features.Volume.Get = true
+				features.Volume.SingleNodeMultiWriter = true
+				features.Volume.VolumeModes = []crdv1alpha1.VolumeModeCapability{
+					{VolumeMode: corev1.PersistentVolumeBlock, AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+				}
+				features.Node = crdv1alpha1.ProvisionerCapabilitySpecFeaturesNode{
+					StageUnstage:          true,
+					Expand:                true,
+					Condition:             true,
+					SingleNodeMultiWriter: true,
+				}
+			},
+		},
+		{
+			name:       "unknown operator never matches",
+			parameters: map[string]string{"type": "local"},
+			overrides: []*crdv1alpha1.CapabilityOverride{newCapabilityOverride("typo", "csi.example.com", crdv1alpha1.CapabilityOverrideRule{
+				Parameters: []crdv1alpha1.ParameterMatcher{{Key: "type", Operator: "Equals", Values: []string{"local"}}},
+				Features:   crdv1alpha1.FeaturesOverride{Topology: &disabled},
+			})},
+			expect: func(features *crdv1alpha1.StorageClassCapabilitySpecFeatures) {},
+		},
+	}
+	pcap := newProvisionerCapability("csi.example.com")
+	for _, test := range tests {
+		sc := newStorageClass("sc-example", "csi.example.com")
+		sc.Parameters = test.parameters
+		snapClass := newSnapshotClass("sc-example", "csi.example.com")
+
+		expected := crdv1alpha1.StorageClassCapabilitySpecFeatures{
+			Topology: pcap.Spec.Features.Topology,
+			Volume:   pcap.Spec.Features.Volume,
+			Snapshot: pcap.Spec.Features.Snapshot,
+			Node:     pcap.Spec.Features.Node,
+		}
+		test.expect(&expected)
+		sccap := newSccap(sc, snapClass, nil, pcap, test.overrides)
+		if !reflect.DeepEqual(expected, sccap.Spec.Features) {
+			t.Errorf("%s: wrong features\nDiff:\n %s", test.name, diff.ObjectGoPrintSideBySide(expected, sccap.Spec.Features))
+		}
+	}
+}
+
+func TestChooseSnapshotClass(t *testing.T) {
+	newDefaultSnapshotClass := func(name string, provisioner string) *snapshot.VolumeSnapshotClass {
+		snapClass := newSnapshotClass(name, provisioner)
+		snapClass.Annotations = map[string]string{AnnotationIsDefaultSnapshotClass: "true"}
+		return snapClass
+	}
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		snapClasses   []*snapshot.VolumeSnapshotClass
+		expected      string
+		expectedNames []string
+	}{
+		{
+			name:        "no snapshot class",
+			snapClasses: nil,
+			expected:    "",
+		},
+		{
+			name: "match by driver instead of name",
+			snapClasses: []*snapshot.VolumeSnapshotClass{
+				newSnapshotClass("sc-example", "csi.other.com"),
+				newSnapshotClass("snap-b", "csi.example.com"),
+				newSnapshotClass("snap-a", "csi.example.com"),
+			},
+			expected:      "snap-a",
+			expectedNames: []string{"snap-a", "snap-b"},
+		},
+		{
+			name: "default snapshot class",
+			snapClasses: []*snapshot.VolumeSnapshotClass{
+				newSnapshotClass("snap-a", "csi.example.com"),
+				newDefaultSnapshotClass("snap-b", "csi.example.com"),
+				newDefaultSnapshotClass("snap-c", "csi.other.com"),
+			},
+			expected:      "snap-b",
+			expectedNames: []string{"snap-a", "snap-b"},
+		},
+		{
+			name:        "pinned snapshot class",
+			annotations: map[string]string{AnnotationSnapshotClass: "snap-c"},
+			snapClasses: []*snapshot.VolumeSnapshotClass{
+				newDefaultSnapshotClass("snap-b", "csi.example.com"),
+				newSnapshotClass("snap-c", "csi.example.com"),
+			},
+			expected:      "snap-c",
+			expectedNames: []string{"snap-b", "snap-c"},
+		},
+		{
+			name:        "pinned snapshot class of another driver",
+			annotations: map[string]string{AnnotationSnapshotClass: "snap-c"},
+			snapClasses: []*snapshot.VolumeSnapshotClass{
+				newDefaultSnapshotClass("snap-b", "csi.example.com"),
+				newSnapshotClass("snap-c", "csi.other.com"),
+			},
+			expected:      "",
+			expectedNames: []string{"snap-b"},
+		},
+	}
+	pcap := newProvisionerCapability("csi.example.com")
+	for _, test := range tests {
+		sc := newStorageClass("sc-example", "csi.example.com")
+		sc.Annotations = test.annotations
+		snapClass := chooseSnapshotClass(sc, test.snapClasses)
+		name := ""
+		if snapClass != nil {
+			name = snapClass.GetName()
+		}
+		if name != test.expected {
+			t.Errorf("%s: expect snapshot class %q, got %q", test.name, test.expected, name)
+		}
+		sccap := newSccap(sc, snapClass, snapshotClassNames(sc, test.snapClasses), pcap, nil)
+		if sccap.Spec.SnapshotClassName != test.expected {
+			t.Errorf("%s: expect snapshotClassName %q, got %q", test.name, test.expected, sccap.Spec.SnapshotClassName)
+		}
+		if !reflect.DeepEqual(sccap.Spec.SnapshotClassNames, test.expectedNames) {
+			t.Errorf("%s: expect snapshotClassNames %v, got %v", test.name, test.expectedNames, sccap.Spec.SnapshotClassNames)
+		}
+		if snapshotSupported := sccap.Spec.Features.Snapshot.Create; snapshotSupported != (test.expected != "") {
+			t.Errorf("%s: expect snapshot create %v, got %v", test.name, test.expected != "", snapshotSupported)
+		}
+	}
+}
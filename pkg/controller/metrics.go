@@ -0,0 +1,89 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package controller
+
+import (
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// Register workqueue depth, latency and retry metrics of the controller workqueue.
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+	"time"
+)
+
+const (
+	metricsNamespace = "storage_capability"
+	metricsSubsystem = "controller"
+)
+
+var (
+	syncTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "sync_total",
+			Help:           "Number of StorageClassCapability syncs per StorageClass and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"storage_class", "result"},
+	)
+	syncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "sync_duration_seconds",
+			Help:           "Duration of StorageClassCapability syncs in seconds.",
+			Buckets:        metrics.DefBuckets,
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+	provisionerLastProbe = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "provisioner_last_probe_timestamp_seconds",
+			Help:           "Unix time of the last successful probe recorded in ProvisionerCapability.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"provisioner"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(syncTotal, syncDuration, provisionerLastProbe)
+}
+
+// recordSync records the result and duration of syncing the StorageClass.
+func recordSync(storageClass string, err error, start time.Time) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	syncTotal.WithLabelValues(storageClass, result).Inc()
+	syncDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+// recordLastProbe exports the last probe time of ProvisionerCapability, so stale capabilities can be alerted on.
+func recordLastProbe(pcap *crdapi.ProvisionerCapability) {
+	if pcap.Status.LastProbeTime == nil {
+		return
+	}
+	provisionerLastProbe.WithLabelValues(pcap.GetName()).Set(float64(pcap.Status.LastProbeTime.Unix()))
+}
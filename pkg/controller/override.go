@@ -0,0 +1,127 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package controller
+
+import (
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sort"
+)
+
+// listCapabilityOverrides returns the CapabilityOverrides of the provisioner sorted by name,
+// so that the result of evaluation does not depend on the order of the lister.
+func (c *Controller) listCapabilityOverrides(provisioner string) ([]*crdapi.CapabilityOverride, error) {
+	all, err := c.overrideLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var res []*crdapi.CapabilityOverride
+	for _, override := range all {
+		if override.Spec.Provisioner == provisioner {
+			res = append(res, override)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].GetName() < res[j].GetName()
+	})
+	return res, nil
+}
+
+// applyCapabilityOverrides applies the rules matching the StorageClass parameters to the features.
+func applyCapabilityOverrides(features *crdapi.StorageClassCapabilitySpecFeatures, parameters map[string]string, overrides []*crdapi.CapabilityOverride) {
+	for _, override := range overrides {
+		for _, rule := range override.Spec.Rules {
+			if matchParameters(parameters, rule.Parameters) {
+				applyFeaturesOverride(features, &rule.Features)
+			}
+		}
+	}
+}
+
+func matchParameters(parameters map[string]string, matchers []crdapi.ParameterMatcher) bool {
+	for _, matcher := range matchers {
+		value, exists := parameters[matcher.Key]
+		switch matcher.Operator {
+		case crdapi.ParameterMatchIn:
+			if !exists || !sets.NewString(matcher.Values...).Has(value) {
+				return false
+			}
+		case crdapi.ParameterMatchNotIn:
+			if exists && sets.NewString(matcher.Values...).Has(value) {
+				return false
+			}
+		case crdapi.ParameterMatchExists:
+			if !exists {
+				return false
+			}
+		case crdapi.ParameterMatchDoesNotExist:
+			if exists {
+				return false
+			}
+		default:
+			// Unknown operator never matches, so a typo does not change every StorageClass.
+			return false
+		}
+	}
+	return true
+}
+
+func applyFeaturesOverride(features *crdapi.StorageClassCapabilitySpecFeatures, override *crdapi.FeaturesOverride) {
+	setBool(&features.Topology, override.Topology)
+	if v := override.Volume; v != nil {
+		setBool(&features.Volume.Create, v.Create)
+		setBool(&features.Volume.Attach, v.Attach)
+		setBool(&features.Volume.List, v.List)
+		setBool(&features.Volume.Clone, v.Clone)
+		setBool(&features.Volume.Stats, v.Stats)
+		if v.Expand != nil {
+			features.Volume.Expand = *v.Expand
+		}
+		setBool(&features.Volume.Capacity, v.Capacity)
+		setBool(&features.Volume.ReadOnlyAttach, v.ReadOnlyAttach)
+		setBool(&features.Volume.ControllerExpand, v.ControllerExpand)
+		setBool(&features.Volume.ListPublishedNodes, v.ListPublishedNodes)
+		setBool(&features.Volume.Condition, v.Condition)
+		setBool(&features.Volume.Get, v.Get)
+		setBool(&features.Volume.SingleNodeMultiWriter, v.SingleNodeMultiWriter)
+		if v.VolumeModes != nil {
+			features.Volume.VolumeModes = make([]crdapi.VolumeModeCapability, len(v.VolumeModes))
+			for i := range v.VolumeModes {
+				v.VolumeModes[i].DeepCopyInto(&features.Volume.VolumeModes[i])
+			}
+		}
+	}
+	if s := override.Snapshot; s != nil {
+		setBool(&features.Snapshot.Create, s.Create)
+		setBool(&features.Snapshot.List, s.List)
+	}
+	if n := override.Node; n != nil {
+		setBool(&features.Node.StageUnstage, n.StageUnstage)
+		setBool(&features.Node.Expand, n.Expand)
+		setBool(&features.Node.Condition, n.Condition)
+		setBool(&features.Node.SingleNodeMultiWriter, n.SingleNodeMultiWriter)
+	}
+}
+
+func setBool(dst *bool, src *bool) {
+	if src != nil {
+		*dst = *src
+	}
+}
@@ -0,0 +1,110 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package controller
+
+import (
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog"
+)
+
+const (
+	// LabelProvisionerCapability records the ProvisionerCapability a StorageClassCapability is derived from.
+	LabelProvisionerCapability = "storage.kubesphere.io/provisioner-capability"
+	// LabelSnapshotClass records the VolumeSnapshotClass a StorageClassCapability is derived from.
+	LabelSnapshotClass = "storage.kubesphere.io/volume-snapshot-class"
+)
+
+var storageClassKind = v1.SchemeGroupVersion.WithKind("StorageClass")
+
+// newStorageClassOwnerReference returns the controller reference from StorageClassCapability to StorageClass,
+// so the garbage collector removes StorageClassCapability with its StorageClass.
+func newStorageClassOwnerReference(sc *v1.StorageClass) metav1.OwnerReference {
+	isController := true
+	return metav1.OwnerReference{
+		APIVersion: storageClassKind.GroupVersion().String(),
+		Kind:       storageClassKind.Kind,
+		Name:       sc.GetName(),
+		UID:        sc.GetUID(),
+		Controller: &isController,
+	}
+}
+
+// setSccapOwner points the owner reference of StorageClassCapability to the StorageClass and tracks the
+// ProvisionerCapability and VolumeSnapshotClass in labels. They are not owners, because the garbage collector
+// keeps an object as long as any of its owners exists.
+func setSccapOwner(meta *metav1.ObjectMeta, sc *v1.StorageClass, pcap *crdapi.ProvisionerCapability, snapClassName string) {
+	ownerRefs := []metav1.OwnerReference{newStorageClassOwnerReference(sc)}
+	for _, ref := range meta.OwnerReferences {
+		if ref.APIVersion != ownerRefs[0].APIVersion || ref.Kind != ownerRefs[0].Kind {
+			ownerRefs = append(ownerRefs, ref)
+		}
+	}
+	meta.OwnerReferences = ownerRefs
+
+	pcapName := ""
+	if pcap != nil {
+		pcapName = pcap.GetName()
+	}
+	setTrackingLabel(meta, LabelProvisionerCapability, pcapName)
+	setTrackingLabel(meta, LabelSnapshotClass, snapClassName)
+}
+
+// setTrackingLabel sets the label, or removes it if the value is empty or not a valid label value.
+func setTrackingLabel(meta *metav1.ObjectMeta, key string, value string) {
+	if value == "" || len(validation.IsValidLabelValue(value)) != 0 {
+		delete(meta.Labels, key)
+		if len(meta.Labels) == 0 {
+			meta.Labels = nil
+		}
+		return
+	}
+	if meta.Labels == nil {
+		meta.Labels = map[string]string{}
+	}
+	meta.Labels[key] = value
+}
+
+// deleteOrphanedSccaps removes StorageClassCapabilities whose StorageClass does not exist. StorageClasses
+// deleted while the controller is down are not seen by the informer, so the workqueue never gets their keys.
+func (c *Controller) deleteOrphanedSccaps() error {
+	sccaps, err := c.sccapLister.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, sccap := range sccaps {
+		_, err := c.scLister.Get(sccap.GetName())
+		if err == nil {
+			continue
+		}
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		klog.V(4).Infof("Delete orphaned StorageClassCapability %s", sccap.GetName())
+		err = c.crdclientset.StorageV1alpha1().StorageClassCapabilities().Delete(sccap.GetName(), &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package controller
+
+import (
+	"fmt"
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	"k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sort"
+)
+
+const (
+	// AnnotationSnapshotClass pins the VolumeSnapshotClass of a StorageClass.
+	AnnotationSnapshotClass = "storage.kubesphere.io/snapshot-class"
+	// AnnotationIsDefaultSnapshotClass marks the default VolumeSnapshotClass of a driver.
+	AnnotationIsDefaultSnapshotClass = "snapshot.storage.kubernetes.io/is-default-class"
+)
+
+// chooseSnapshotClass returns the VolumeSnapshotClass used by the StorageClass, or nil if there is none.
+// The class pinned by the StorageClass annotation wins, then the default class of the provisioner,
+// then the first class of the provisioner by name.
+func chooseSnapshotClass(sc *v1.StorageClass, snapClasses []*snapshot.VolumeSnapshotClass) *snapshot.VolumeSnapshotClass {
+	candidates := snapshotClassesOfProvisioner(sc, snapClasses)
+	if pinned, ok := sc.GetAnnotations()[AnnotationSnapshotClass]; ok {
+		for _, snapClass := range candidates {
+			if snapClass.GetName() == pinned {
+				return snapClass
+			}
+		}
+		klog.V(4).Infof("VolumeSnapshotClass %s pinned by StorageClass %s not found for driver %s", pinned, sc.GetName(), sc.Provisioner)
+		return nil
+	}
+	for _, snapClass := range candidates {
+		if snapClass.GetAnnotations()[AnnotationIsDefaultSnapshotClass] == "true" {
+			return snapClass
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// snapshotClassesOfProvisioner returns the VolumeSnapshotClasses of the provisioner of the StorageClass by name.
+func snapshotClassesOfProvisioner(sc *v1.StorageClass, snapClasses []*snapshot.VolumeSnapshotClass) []*snapshot.VolumeSnapshotClass {
+	var candidates []*snapshot.VolumeSnapshotClass
+	for _, snapClass := range snapClasses {
+		if snapClass.Driver == sc.Provisioner {
+			candidates = append(candidates, snapClass)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].GetName() < candidates[j].GetName()
+	})
+	return candidates
+}
+
+// snapshotClassNames returns the names of the VolumeSnapshotClasses of the provisioner of the StorageClass.
+func snapshotClassNames(sc *v1.StorageClass, snapClasses []*snapshot.VolumeSnapshotClass) []string {
+	var names []string
+	for _, snapClass := range snapshotClassesOfProvisioner(sc, snapClasses) {
+		names = append(names, snapClass.GetName())
+	}
+	return names
+}
+
+// newSnapshotCondition tells whether snapshot features of the StorageClass are available, and why not.
+// apiServed is false if snapshot.storage.k8s.io is not served by the API server.
+func newSnapshotCondition(apiServed bool, sc *v1.StorageClass, snapClass *snapshot.VolumeSnapshotClass) crdapi.CapabilityCondition {
+	cond := crdapi.CapabilityCondition{
+		Type:   crdapi.CapabilitySnapshotUnavailable,
+		Status: metav1.ConditionTrue,
+	}
+	switch {
+	case !apiServed:
+		cond.Reason = "SnapshotAPINotServed"
+		cond.Message = fmt.Sprintf("None of the versions %v of %s is served", snapshot.SupportedVersions, snapshot.GroupName)
+	case snapClass != nil:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = "SnapshotClassFound"
+		cond.Message = fmt.Sprintf("VolumeSnapshotClass %s is used", snapClass.GetName())
+	case sc.GetAnnotations()[AnnotationSnapshotClass] != "":
+		cond.Reason = "SnapshotClassNotFound"
+		cond.Message = fmt.Sprintf("VolumeSnapshotClass %s of driver %s not found", sc.GetAnnotations()[AnnotationSnapshotClass], sc.Provisioner)
+	default:
+		cond.Reason = "SnapshotClassNotFound"
+		cond.Message = fmt.Sprintf("No VolumeSnapshotClass of driver %s found", sc.Provisioner)
+	}
+	return cond
+}
@@ -0,0 +1,114 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package controller
+
+import (
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sort"
+	"time"
+)
+
+// aggregateTopology collects the topology keys and the distinct segments reported by the
+// node plugins of the provisioner. NodeCapabilities of nodes not in the given set and those
+// not ready are skipped. It returns nil if no node reports any segment.
+func aggregateTopology(provisioner string, ncaps []*crdapi.NodeCapability, nodes sets.String, now time.Time) *crdapi.TopologyStatus {
+	keys := sets.NewString()
+	segments := map[string]map[string]string{}
+	for _, ncap := range ncaps {
+		if ncap.Spec.PluginInfo.Name != provisioner || len(ncap.Spec.AccessibleTopology) == 0 {
+			continue
+		}
+		if !nodes.Has(ncap.Spec.NodeName) || !nodeCapabilityReady(ncap, now) {
+			continue
+		}
+		for k := range ncap.Spec.AccessibleTopology {
+			keys.Insert(k)
+		}
+		segments[labels.Set(ncap.Spec.AccessibleTopology).String()] = ncap.Spec.AccessibleTopology
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+	return newTopologyStatus(keys.List(), segments)
+}
+
+// nodeCapabilityReady returns whether the node plugin was probed successfully within StaleThreshold.
+func nodeCapabilityReady(ncap *crdapi.NodeCapability, now time.Time) bool {
+	if !ncap.Status.IsConditionTrue(crdapi.CapabilityReady) || ncap.Status.IsConditionTrue(crdapi.CapabilityStale) {
+		return false
+	}
+	return ncap.Status.LastProbeTime != nil && now.Sub(ncap.Status.LastProbeTime.Time) <= StaleThreshold
+}
+
+// filterTopology returns the segments of the topology allowed by the AllowedTopologies of a StorageClass.
+// A segment is allowed if it matches any of the terms. All segments are allowed if there is no term.
+func filterTopology(topology *crdapi.TopologyStatus, allowedTopologies []corev1.TopologySelectorTerm) *crdapi.TopologyStatus {
+	if topology == nil {
+		return nil
+	}
+	if len(allowedTopologies) == 0 {
+		return topology.DeepCopy()
+	}
+	segments := map[string]map[string]string{}
+	for _, segment := range topology.Segments {
+		for _, term := range allowedTopologies {
+			if matchTopologySelectorTerm(segment, term) {
+				segments[labels.Set(segment).String()] = segment
+				break
+			}
+		}
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+	return newTopologyStatus(topology.Keys, segments)
+}
+
+func matchTopologySelectorTerm(segment map[string]string, term corev1.TopologySelectorTerm) bool {
+	for _, exp := range term.MatchLabelExpressions {
+		value, ok := segment[exp.Key]
+		if !ok || !sets.NewString(exp.Values...).Has(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// newTopologyStatus sorts segments by their string form so the result is stable across syncs.
+func newTopologyStatus(keys []string, segments map[string]map[string]string) *crdapi.TopologyStatus {
+	res := &crdapi.TopologyStatus{
+		Keys: append([]string(nil), keys...),
+	}
+	ids := make([]string, 0, len(segments))
+	for id := range segments {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		segment := make(map[string]string, len(segments[id]))
+		for k, v := range segments[id] {
+			segment[k] = v
+		}
+		res.Segments = append(res.Segments, segment)
+	}
+	return res
+}
@@ -22,6 +22,8 @@ import (
 	clientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
 	storagev1alpha1 "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/typed/storagecapability/v1alpha1"
 	fakestoragev1alpha1 "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/typed/storagecapability/v1alpha1/fake"
+	storagev1beta1 "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/typed/storagecapability/v1beta1"
+	fakestoragev1beta1 "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/typed/storagecapability/v1beta1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -80,3 +82,8 @@ var _ clientset.Interface = &Clientset{}
 func (c *Clientset) StorageV1alpha1() storagev1alpha1.StorageV1alpha1Interface {
 	return &fakestoragev1alpha1.FakeStorageV1alpha1{Fake: &c.Fake}
 }
+
+// StorageV1beta1 retrieves the StorageV1beta1Client
+func (c *Clientset) StorageV1beta1() storagev1beta1.StorageV1beta1Interface {
+	return &fakestoragev1beta1.FakeStorageV1beta1{Fake: &c.Fake}
+}
@@ -20,6 +20,7 @@ package fake
 
 import (
 	storagev1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	storagev1beta1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1beta1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -32,6 +33,7 @@ var codecs = serializer.NewCodecFactory(scheme)
 var parameterCodec = runtime.NewParameterCodec(scheme)
 var localSchemeBuilder = runtime.SchemeBuilder{
 	storagev1alpha1.AddToScheme,
+	storagev1beta1.AddToScheme,
 }
 
 // AddToScheme adds all types of this clientset into the given scheme. This allows composition
@@ -0,0 +1,164 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	scheme "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// CapabilityOverridesGetter has a method to return a CapabilityOverrideInterface.
+// A group's client should implement this interface.
+type CapabilityOverridesGetter interface {
+	CapabilityOverrides() CapabilityOverrideInterface
+}
+
+// CapabilityOverrideInterface has methods to work with CapabilityOverride resources.
+type CapabilityOverrideInterface interface {
+	Create(*v1alpha1.CapabilityOverride) (*v1alpha1.CapabilityOverride, error)
+	Update(*v1alpha1.CapabilityOverride) (*v1alpha1.CapabilityOverride, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.CapabilityOverride, error)
+	List(opts v1.ListOptions) (*v1alpha1.CapabilityOverrideList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.CapabilityOverride, err error)
+	CapabilityOverrideExpansion
+}
+
+// capabilityOverrides implements CapabilityOverrideInterface
+type capabilityOverrides struct {
+	client rest.Interface
+}
+
+// newCapabilityOverrides returns a CapabilityOverrides
+func newCapabilityOverrides(c *StorageV1alpha1Client) *capabilityOverrides {
+	return &capabilityOverrides{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the capabilityOverride, and returns the corresponding capabilityOverride object, and an error if there is any.
+func (c *capabilityOverrides) Get(name string, options v1.GetOptions) (result *v1alpha1.CapabilityOverride, err error) {
+	result = &v1alpha1.CapabilityOverride{}
+	err = c.client.Get().
+		Resource("capabilityoverrides").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of CapabilityOverrides that match those selectors.
+func (c *capabilityOverrides) List(opts v1.ListOptions) (result *v1alpha1.CapabilityOverrideList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.CapabilityOverrideList{}
+	err = c.client.Get().
+		Resource("capabilityoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested capabilityOverrides.
+func (c *capabilityOverrides) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("capabilityoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a capabilityOverride and creates it.  Returns the server's representation of the capabilityOverride, and an error, if there is any.
+func (c *capabilityOverrides) Create(capabilityOverride *v1alpha1.CapabilityOverride) (result *v1alpha1.CapabilityOverride, err error) {
+	result = &v1alpha1.CapabilityOverride{}
+	err = c.client.Post().
+		Resource("capabilityoverrides").
+		Body(capabilityOverride).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a capabilityOverride and updates it. Returns the server's representation of the capabilityOverride, and an error, if there is any.
+func (c *capabilityOverrides) Update(capabilityOverride *v1alpha1.CapabilityOverride) (result *v1alpha1.CapabilityOverride, err error) {
+	result = &v1alpha1.CapabilityOverride{}
+	err = c.client.Put().
+		Resource("capabilityoverrides").
+		Name(capabilityOverride.Name).
+		Body(capabilityOverride).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the capabilityOverride and deletes it. Returns an error if one occurs.
+func (c *capabilityOverrides) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("capabilityoverrides").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *capabilityOverrides) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("capabilityoverrides").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched capabilityOverride.
+func (c *capabilityOverrides) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.CapabilityOverride, err error) {
+	result = &v1alpha1.CapabilityOverride{}
+	err = c.client.Patch(pt).
+		Resource("capabilityoverrides").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
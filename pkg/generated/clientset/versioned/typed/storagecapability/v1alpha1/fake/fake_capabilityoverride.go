@@ -0,0 +1,120 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeCapabilityOverrides implements CapabilityOverrideInterface
+type FakeCapabilityOverrides struct {
+	Fake *FakeStorageV1alpha1
+}
+
+var capabilityoverridesResource = schema.GroupVersionResource{Group: "storage.kubesphere.io", Version: "v1alpha1", Resource: "capabilityoverrides"}
+
+var capabilityoverridesKind = schema.GroupVersionKind{Group: "storage.kubesphere.io", Version: "v1alpha1", Kind: "CapabilityOverride"}
+
+// Get takes name of the capabilityOverride, and returns the corresponding capabilityOverride object, and an error if there is any.
+func (c *FakeCapabilityOverrides) Get(name string, options v1.GetOptions) (result *v1alpha1.CapabilityOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(capabilityoverridesResource, name), &v1alpha1.CapabilityOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.CapabilityOverride), err
+}
+
+// List takes label and field selectors, and returns the list of CapabilityOverrides that match those selectors.
+func (c *FakeCapabilityOverrides) List(opts v1.ListOptions) (result *v1alpha1.CapabilityOverrideList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(capabilityoverridesResource, capabilityoverridesKind, opts), &v1alpha1.CapabilityOverrideList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.CapabilityOverrideList{ListMeta: obj.(*v1alpha1.CapabilityOverrideList).ListMeta}
+	for _, item := range obj.(*v1alpha1.CapabilityOverrideList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested capabilityOverrides.
+func (c *FakeCapabilityOverrides) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(capabilityoverridesResource, opts))
+}
+
+// Create takes the representation of a capabilityOverride and creates it.  Returns the server's representation of the capabilityOverride, and an error, if there is any.
+func (c *FakeCapabilityOverrides) Create(capabilityOverride *v1alpha1.CapabilityOverride) (result *v1alpha1.CapabilityOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(capabilityoverridesResource, capabilityOverride), &v1alpha1.CapabilityOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.CapabilityOverride), err
+}
+
+// Update takes the representation of a capabilityOverride and updates it. Returns the server's representation of the capabilityOverride, and an error, if there is any.
+func (c *FakeCapabilityOverrides) Update(capabilityOverride *v1alpha1.CapabilityOverride) (result *v1alpha1.CapabilityOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(capabilityoverridesResource, capabilityOverride), &v1alpha1.CapabilityOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.CapabilityOverride), err
+}
+
+// Delete takes name of the capabilityOverride and deletes it. Returns an error if one occurs.
+func (c *FakeCapabilityOverrides) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(capabilityoverridesResource, name), &v1alpha1.CapabilityOverride{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeCapabilityOverrides) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(capabilityoverridesResource, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.CapabilityOverrideList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched capabilityOverride.
+func (c *FakeCapabilityOverrides) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.CapabilityOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(capabilityoverridesResource, name, pt, data, subresources...), &v1alpha1.CapabilityOverride{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.CapabilityOverride), err
+}
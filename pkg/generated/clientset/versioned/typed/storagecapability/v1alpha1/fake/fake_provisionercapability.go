@@ -94,6 +94,17 @@ func (c *FakeProvisionerCapabilities) Update(provisionerCapability *v1alpha1.Pro
 	return obj.(*v1alpha1.ProvisionerCapability), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeProvisionerCapabilities) UpdateStatus(provisionerCapability *v1alpha1.ProvisionerCapability) (*v1alpha1.ProvisionerCapability, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(provisionercapabilitiesResource, "status", provisionerCapability), &v1alpha1.ProvisionerCapability{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ProvisionerCapability), err
+}
+
 // Delete takes name of the provisionerCapability and deletes it. Returns an error if one occurs.
 func (c *FakeProvisionerCapabilities) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -28,6 +28,14 @@ type FakeStorageV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeStorageV1alpha1) CapabilityOverrides() v1alpha1.CapabilityOverrideInterface {
+	return &FakeCapabilityOverrides{c}
+}
+
+func (c *FakeStorageV1alpha1) NodeCapabilities() v1alpha1.NodeCapabilityInterface {
+	return &FakeNodeCapabilities{c}
+}
+
 func (c *FakeStorageV1alpha1) ProvisionerCapabilities() v1alpha1.ProvisionerCapabilityInterface {
 	return &FakeProvisionerCapabilities{c}
 }
@@ -94,6 +94,17 @@ func (c *FakeStorageClassCapabilities) Update(storageClassCapability *v1alpha1.S
 	return obj.(*v1alpha1.StorageClassCapability), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeStorageClassCapabilities) UpdateStatus(storageClassCapability *v1alpha1.StorageClassCapability) (*v1alpha1.StorageClassCapability, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateSubresourceAction(storageclasscapabilitiesResource, "status", storageClassCapability), &v1alpha1.StorageClassCapability{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.StorageClassCapability), err
+}
+
 // Delete takes name of the storageClassCapability and deletes it. Returns an error if one occurs.
 func (c *FakeStorageClassCapabilities) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
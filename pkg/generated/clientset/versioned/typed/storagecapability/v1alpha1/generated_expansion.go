@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha1
 
+type CapabilityOverrideExpansion interface{}
+
+type NodeCapabilityExpansion interface{}
+
 type ProvisionerCapabilityExpansion interface{}
 
 type StorageClassCapabilityExpansion interface{}
@@ -39,6 +39,7 @@ type ProvisionerCapabilitiesGetter interface {
 type ProvisionerCapabilityInterface interface {
 	Create(*v1alpha1.ProvisionerCapability) (*v1alpha1.ProvisionerCapability, error)
 	Update(*v1alpha1.ProvisionerCapability) (*v1alpha1.ProvisionerCapability, error)
+	UpdateStatus(*v1alpha1.ProvisionerCapability) (*v1alpha1.ProvisionerCapability, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1alpha1.ProvisionerCapability, error)
@@ -125,6 +126,21 @@ func (c *provisionerCapabilities) Update(provisionerCapability *v1alpha1.Provisi
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *provisionerCapabilities) UpdateStatus(provisionerCapability *v1alpha1.ProvisionerCapability) (result *v1alpha1.ProvisionerCapability, err error) {
+	result = &v1alpha1.ProvisionerCapability{}
+	err = c.client.Put().
+		Resource("provisionercapabilities").
+		Name(provisionerCapability.Name).
+		SubResource("status").
+		Body(provisionerCapability).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the provisionerCapability and deletes it. Returns an error if one occurs.
 func (c *provisionerCapabilities) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
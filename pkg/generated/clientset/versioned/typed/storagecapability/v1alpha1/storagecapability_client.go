@@ -26,6 +26,8 @@ import (
 
 type StorageV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	CapabilityOverridesGetter
+	NodeCapabilitiesGetter
 	ProvisionerCapabilitiesGetter
 	StorageClassCapabilitiesGetter
 }
@@ -35,6 +37,14 @@ type StorageV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *StorageV1alpha1Client) CapabilityOverrides() CapabilityOverrideInterface {
+	return newCapabilityOverrides(c)
+}
+
+func (c *StorageV1alpha1Client) NodeCapabilities() NodeCapabilityInterface {
+	return newNodeCapabilities(c)
+}
+
 func (c *StorageV1alpha1Client) ProvisionerCapabilities() ProvisionerCapabilityInterface {
 	return newProvisionerCapabilities(c)
 }
@@ -39,6 +39,7 @@ type StorageClassCapabilitiesGetter interface {
 type StorageClassCapabilityInterface interface {
 	Create(*v1alpha1.StorageClassCapability) (*v1alpha1.StorageClassCapability, error)
 	Update(*v1alpha1.StorageClassCapability) (*v1alpha1.StorageClassCapability, error)
+	UpdateStatus(*v1alpha1.StorageClassCapability) (*v1alpha1.StorageClassCapability, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1alpha1.StorageClassCapability, error)
@@ -125,6 +126,21 @@ func (c *storageClassCapabilities) Update(storageClassCapability *v1alpha1.Stora
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *storageClassCapabilities) UpdateStatus(storageClassCapability *v1alpha1.StorageClassCapability) (result *v1alpha1.StorageClassCapability, err error) {
+	result = &v1alpha1.StorageClassCapability{}
+	err = c.client.Put().
+		Resource("storageclasscapabilities").
+		Name(storageClassCapability.Name).
+		SubResource("status").
+		Body(storageClassCapability).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the storageClassCapability and deletes it. Returns an error if one occurs.
 func (c *storageClassCapabilities) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	v1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	v1beta1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1beta1"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 )
@@ -53,11 +54,21 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=storage.kubesphere.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("capabilityoverrides"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().CapabilityOverrides().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("nodecapabilities"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().NodeCapabilities().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("provisionercapabilities"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().ProvisionerCapabilities().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("storageclasscapabilities"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().StorageClassCapabilities().Informer()}, nil
 
+		// Group=storage.kubesphere.io, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("provisionercapabilities"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1beta1().ProvisionerCapabilities().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("storageclasscapabilities"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1beta1().StorageClassCapabilities().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
@@ -24,6 +24,10 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// CapabilityOverrides returns a CapabilityOverrideInformer.
+	CapabilityOverrides() CapabilityOverrideInformer
+	// NodeCapabilities returns a NodeCapabilityInformer.
+	NodeCapabilities() NodeCapabilityInformer
 	// ProvisionerCapabilities returns a ProvisionerCapabilityInformer.
 	ProvisionerCapabilities() ProvisionerCapabilityInformer
 	// StorageClassCapabilities returns a StorageClassCapabilityInformer.
@@ -41,6 +45,16 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// CapabilityOverrides returns a CapabilityOverrideInformer.
+func (v *version) CapabilityOverrides() CapabilityOverrideInformer {
+	return &capabilityOverrideInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
+// NodeCapabilities returns a NodeCapabilityInformer.
+func (v *version) NodeCapabilities() NodeCapabilityInformer {
+	return &nodeCapabilityInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // ProvisionerCapabilities returns a ProvisionerCapabilityInformer.
 func (v *version) ProvisionerCapabilities() ProvisionerCapabilityInformer {
 	return &provisionerCapabilityInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
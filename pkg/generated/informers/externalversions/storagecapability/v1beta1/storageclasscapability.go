@@ -0,0 +1,88 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	time "time"
+
+	storagecapabilityv1beta1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1beta1"
+	versioned "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
+	internalinterfaces "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions/internalinterfaces"
+	v1beta1 "github.com/kubesphere/storage-capability/pkg/generated/listers/storagecapability/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// StorageClassCapabilityInformer provides access to a shared informer and lister for
+// StorageClassCapabilities.
+type StorageClassCapabilityInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1beta1.StorageClassCapabilityLister
+}
+
+type storageClassCapabilityInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewStorageClassCapabilityInformer constructs a new informer for StorageClassCapability type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewStorageClassCapabilityInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredStorageClassCapabilityInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredStorageClassCapabilityInformer constructs a new informer for StorageClassCapability type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredStorageClassCapabilityInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.StorageV1beta1().StorageClassCapabilities().List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.StorageV1beta1().StorageClassCapabilities().Watch(options)
+			},
+		},
+		&storagecapabilityv1beta1.StorageClassCapability{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *storageClassCapabilityInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredStorageClassCapabilityInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *storageClassCapabilityInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&storagecapabilityv1beta1.StorageClassCapability{}, f.defaultInformer)
+}
+
+func (f *storageClassCapabilityInformer) Lister() v1beta1.StorageClassCapabilityLister {
+	return v1beta1.NewStorageClassCapabilityLister(f.Informer().GetIndexer())
+}
@@ -0,0 +1,65 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CapabilityOverrideLister helps list CapabilityOverrides.
+type CapabilityOverrideLister interface {
+	// List lists all CapabilityOverrides in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.CapabilityOverride, err error)
+	// Get retrieves the CapabilityOverride from the index for a given name.
+	Get(name string) (*v1alpha1.CapabilityOverride, error)
+	CapabilityOverrideListerExpansion
+}
+
+// capabilityOverrideLister implements the CapabilityOverrideLister interface.
+type capabilityOverrideLister struct {
+	indexer cache.Indexer
+}
+
+// NewCapabilityOverrideLister returns a new CapabilityOverrideLister.
+func NewCapabilityOverrideLister(indexer cache.Indexer) CapabilityOverrideLister {
+	return &capabilityOverrideLister{indexer: indexer}
+}
+
+// List lists all CapabilityOverrides in the indexer.
+func (s *capabilityOverrideLister) List(selector labels.Selector) (ret []*v1alpha1.CapabilityOverride, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.CapabilityOverride))
+	})
+	return ret, err
+}
+
+// Get retrieves the CapabilityOverride from the index for a given name.
+func (s *capabilityOverrideLister) Get(name string) (*v1alpha1.CapabilityOverride, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("capabilityoverride"), name)
+	}
+	return obj.(*v1alpha1.CapabilityOverride), nil
+}
@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+// CapabilityOverrideListerExpansion allows custom methods to be added to
+// CapabilityOverrideLister.
+type CapabilityOverrideListerExpansion interface{}
+
+// NodeCapabilityListerExpansion allows custom methods to be added to
+// NodeCapabilityLister.
+type NodeCapabilityListerExpansion interface{}
+
 // ProvisionerCapabilityListerExpansion allows custom methods to be added to
 // ProvisionerCapabilityLister.
 type ProvisionerCapabilityListerExpansion interface{}
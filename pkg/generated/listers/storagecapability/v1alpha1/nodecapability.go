@@ -0,0 +1,65 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodeCapabilityLister helps list NodeCapabilities.
+type NodeCapabilityLister interface {
+	// List lists all NodeCapabilities in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.NodeCapability, err error)
+	// Get retrieves the NodeCapability from the index for a given name.
+	Get(name string) (*v1alpha1.NodeCapability, error)
+	NodeCapabilityListerExpansion
+}
+
+// nodeCapabilityLister implements the NodeCapabilityLister interface.
+type nodeCapabilityLister struct {
+	indexer cache.Indexer
+}
+
+// NewNodeCapabilityLister returns a new NodeCapabilityLister.
+func NewNodeCapabilityLister(indexer cache.Indexer) NodeCapabilityLister {
+	return &nodeCapabilityLister{indexer: indexer}
+}
+
+// List lists all NodeCapabilities in the indexer.
+func (s *nodeCapabilityLister) List(selector labels.Selector) (ret []*v1alpha1.NodeCapability, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.NodeCapability))
+	})
+	return ret, err
+}
+
+// Get retrieves the NodeCapability from the index for a given name.
+func (s *nodeCapabilityLister) Get(name string) (*v1alpha1.NodeCapability, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("nodecapability"), name)
+	}
+	return obj.(*v1alpha1.NodeCapability), nil
+}
@@ -20,6 +20,7 @@ package handler
 
 import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	corev1 "k8s.io/api/core/v1"
 	"time"
 
 	"context"
@@ -30,6 +31,8 @@ import (
 
 type PluginHandler interface {
 	GetFullCapability() (*v1alpha1.ProvisionerCapabilitySpec, error)
+	GetNodeCapability(nodeName string) (*v1alpha1.NodeCapabilitySpec, error)
+	GetVolumeModes(probeVolumeID string) ([]v1alpha1.VolumeModeCapability, error)
 }
 
 type plugin struct {
@@ -71,6 +74,7 @@ func (p *plugin) GetIdentityCapability() (topo bool, expand v1alpha1.ExpandMode,
 	if err != nil {
 		return false, v1alpha1.ExpandModeUnknown, err
 	}
+	expand = v1alpha1.ExpandModeUnknown
 	for _, cap := range rsp.GetCapabilities() {
 		if cap == nil {
 			continue
@@ -98,6 +102,15 @@ func (p *plugin) GetIdentityCapability() (topo bool, expand v1alpha1.ExpandMode,
 
 type NodeCapabilitySet map[csi.NodeServiceCapability_RPC_Type]bool
 
+func (s NodeCapabilitySet) features() v1alpha1.ProvisionerCapabilitySpecFeaturesNode {
+	return v1alpha1.ProvisionerCapabilitySpecFeaturesNode{
+		StageUnstage:          s[csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME],
+		Expand:                s[csi.NodeServiceCapability_RPC_EXPAND_VOLUME],
+		Condition:             s[csi.NodeServiceCapability_RPC_VOLUME_CONDITION],
+		SingleNodeMultiWriter: s[csi.NodeServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER],
+	}
+}
+
 func (p *plugin) GetNodeCapabilities() (NodeCapabilitySet, error) {
 	client := csi.NewNodeClient(p.conn)
 	req := csi.NodeGetCapabilitiesRequest{}
@@ -153,11 +166,103 @@ func (p *plugin) GetFullCapability() (*v1alpha1.ProvisionerCapabilitySpec, error
 				Clone:  controllerCapSet[csi.ControllerServiceCapability_RPC_CLONE_VOLUME],
 				Stats:  nodeCapSet[csi.NodeServiceCapability_RPC_GET_VOLUME_STATS],
 				Expand: expand,
+
+				Capacity:              controllerCapSet[csi.ControllerServiceCapability_RPC_GET_CAPACITY],
+				ReadOnlyAttach:        controllerCapSet[csi.ControllerServiceCapability_RPC_PUBLISH_READONLY],
+				ControllerExpand:      controllerCapSet[csi.ControllerServiceCapability_RPC_EXPAND_VOLUME],
+				ListPublishedNodes:    controllerCapSet[csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES],
+				Condition:             controllerCapSet[csi.ControllerServiceCapability_RPC_VOLUME_CONDITION],
+				Get:                   controllerCapSet[csi.ControllerServiceCapability_RPC_GET_VOLUME],
+				SingleNodeMultiWriter: controllerCapSet[csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER],
 			},
 			Snapshot: v1alpha1.ProvisionerCapabilitySpecFeaturesSnapshot{
 				Create: controllerCapSet[csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT],
 				List:   controllerCapSet[csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS],
 			},
+			Node: nodeCapSet.features(),
 		},
 	}, nil
 }
+
+func (p *plugin) GetNodeInfo() (*csi.NodeGetInfoResponse, error) {
+	client := csi.NewNodeClient(p.conn)
+	req := csi.NodeGetInfoRequest{}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	return client.NodeGetInfo(ctx, &req)
+}
+
+// GetNodeCapability probes the node plugin running on the node with the given name.
+func (p *plugin) GetNodeCapability(nodeName string) (*v1alpha1.NodeCapabilitySpec, error) {
+	info, err := p.GetPluginInfo()
+	if err != nil {
+		return nil, err
+	}
+	nodeInfo, err := p.GetNodeInfo()
+	if err != nil {
+		return nil, err
+	}
+	nodeCapSet, err := p.GetNodeCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	segments := nodeInfo.GetAccessibleTopology().GetSegments()
+	if len(segments) == 0 {
+		segments = nil
+	}
+	return &v1alpha1.NodeCapabilitySpec{
+		NodeName:           nodeName,
+		PluginInfo:         *info,
+		NodeID:             nodeInfo.GetNodeId(),
+		MaxVolumesPerNode:  nodeInfo.GetMaxVolumesPerNode(),
+		AccessibleTopology: segments,
+		Features:           nodeCapSet.features(),
+	}, nil
+}
+
+// accessModes maps Kubernetes access modes to CSI access modes, in the order they are reported.
+var accessModes = []struct {
+	mode    corev1.PersistentVolumeAccessMode
+	csiMode csi.VolumeCapability_AccessMode_Mode
+}{
+	{corev1.ReadWriteOnce, csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	{corev1.ReadOnlyMany, csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY},
+	{corev1.ReadWriteMany, csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+}
+
+// GetVolumeModes issues ValidateVolumeCapabilities against an existing probe volume for every
+// combination of volume mode and access mode, and returns the confirmed ones.
+func (p *plugin) GetVolumeModes(probeVolumeID string) ([]v1alpha1.VolumeModeCapability, error) {
+	client := csi.NewControllerClient(p.conn)
+	var res []v1alpha1.VolumeModeCapability
+	for _, volumeMode := range []corev1.PersistentVolumeMode{corev1.PersistentVolumeFilesystem, corev1.PersistentVolumeBlock} {
+		modeCap := v1alpha1.VolumeModeCapability{VolumeMode: volumeMode}
+		for _, accessMode := range accessModes {
+			volCap := &csi.VolumeCapability{
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: accessMode.csiMode},
+			}
+			if volumeMode == corev1.PersistentVolumeBlock {
+				volCap.AccessType = &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}
+			} else {
+				volCap.AccessType = &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}
+			}
+			req := csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId:           probeVolumeID,
+				VolumeCapabilities: []*csi.VolumeCapability{volCap},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+			rsp, err := client.ValidateVolumeCapabilities(ctx, &req)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+			if rsp.GetConfirmed() != nil {
+				modeCap.AccessModes = append(modeCap.AccessModes, accessMode.mode)
+			}
+		}
+		if len(modeCap.AccessModes) > 0 {
+			res = append(res, modeCap)
+		}
+	}
+	return res, nil
+}
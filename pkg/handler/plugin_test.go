@@ -0,0 +1,401 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package handler
+
+import (
+	"context"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testDriver = "csi.example.com"
+
+// fakeIdentityServer, fakeControllerServer and fakeNodeServer report the configured capabilities.
+type fakeIdentityServer struct {
+	csi.UnimplementedIdentityServer
+	pluginCaps []*csi.PluginCapability
+}
+
+func (s *fakeIdentityServer) GetPluginInfo(context.Context, *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+	return &csi.GetPluginInfoResponse{Name: testDriver, VendorVersion: "v1.0.0"}, nil
+}
+
+func (s *fakeIdentityServer) GetPluginCapabilities(context.Context, *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
+	return &csi.GetPluginCapabilitiesResponse{Capabilities: s.pluginCaps}, nil
+}
+
+type fakeControllerServer struct {
+	csi.UnimplementedControllerServer
+	controllerCaps []csi.ControllerServiceCapability_RPC_Type
+	// confirm reports whether ValidateVolumeCapabilities confirms the volume capability.
+	confirm func(volumeID string, volCap *csi.VolumeCapability) bool
+}
+
+func (s *fakeControllerServer) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	if s.confirm == nil {
+		return s.UnimplementedControllerServer.ValidateVolumeCapabilities(ctx, req)
+	}
+	for _, volCap := range req.GetVolumeCapabilities() {
+		if !s.confirm(req.GetVolumeId(), volCap) {
+			return &csi.ValidateVolumeCapabilitiesResponse{Message: "unsupported"}, nil
+		}
+	}
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{VolumeCapabilities: req.GetVolumeCapabilities()},
+	}, nil
+}
+
+func (s *fakeControllerServer) ControllerGetCapabilities(context.Context, *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	rsp := &csi.ControllerGetCapabilitiesResponse{}
+	for _, t := range s.controllerCaps {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{Rpc: &csi.ControllerServiceCapability_RPC{Type: t}},
+		})
+	}
+	return rsp, nil
+}
+
+type fakeNodeServer struct {
+	csi.UnimplementedNodeServer
+	nodeCaps []csi.NodeServiceCapability_RPC_Type
+	nodeInfo *csi.NodeGetInfoResponse
+}
+
+func (s *fakeNodeServer) NodeGetInfo(context.Context, *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	if s.nodeInfo == nil {
+		return &csi.NodeGetInfoResponse{}, nil
+	}
+	return s.nodeInfo, nil
+}
+
+func (s *fakeNodeServer) NodeGetCapabilities(context.Context, *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	rsp := &csi.NodeGetCapabilitiesResponse{}
+	for _, t := range s.nodeCaps {
+		rsp.Capabilities = append(rsp.Capabilities, &csi.NodeServiceCapability{
+			Type: &csi.NodeServiceCapability_Rpc{Rpc: &csi.NodeServiceCapability_RPC{Type: t}},
+		})
+	}
+	return rsp, nil
+}
+
+// newTestPlugin serves the fake servers in memory and returns a plugin connected to them.
+func newTestPlugin(t *testing.T, identity *fakeIdentityServer, controller csi.ControllerServer, node csi.NodeServer) *plugin {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	csi.RegisterIdentityServer(server, identity)
+	csi.RegisterControllerServer(server, controller)
+	csi.RegisterNodeServer(server, node)
+	go server.Serve(listener)
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+	return &plugin{conn: conn, timeout: 10 * time.Second}
+}
+
+func newExpansionCapability(t csi.PluginCapability_VolumeExpansion_Type) *csi.PluginCapability {
+	return &csi.PluginCapability{
+		Type: &csi.PluginCapability_VolumeExpansion_{VolumeExpansion: &csi.PluginCapability_VolumeExpansion{Type: t}},
+	}
+}
+
+func TestGetFullCapability(t *testing.T) {
+	topology := &csi.PluginCapability{
+		Type: &csi.PluginCapability_Service_{Service: &csi.PluginCapability_Service{
+			Type: csi.PluginCapability_Service_VOLUME_ACCESSIBILITY_CONSTRAINTS,
+		}},
+	}
+	allControllerCaps := []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+		csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+		csi.ControllerServiceCapability_RPC_PUBLISH_READONLY,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
+	}
+	tests := []struct {
+		name           string
+		pluginCaps     []*csi.PluginCapability
+		controllerCaps []csi.ControllerServiceCapability_RPC_Type
+		nodeCaps       []csi.NodeServiceCapability_RPC_Type
+		expected       v1alpha1.ProvisionerCapabilitySpecFeatures
+	}{
+		{
+			name: "no capability",
+			expected: v1alpha1.ProvisionerCapabilitySpecFeatures{
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: v1alpha1.ExpandModeUnknown},
+			},
+		},
+		{
+			name:           "all controller capabilities",
+			pluginCaps:     []*csi.PluginCapability{topology},
+			controllerCaps: allControllerCaps,
+			nodeCaps:       []csi.NodeServiceCapability_RPC_Type{csi.NodeServiceCapability_RPC_GET_VOLUME_STATS},
+			expected: v1alpha1.ProvisionerCapabilitySpecFeatures{
+				Topology: true,
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{
+					Create:                true,
+					Attach:                true,
+					List:                  true,
+					Clone:                 true,
+					Stats:                 true,
+					Expand:                v1alpha1.ExpandModeUnknown,
+					Capacity:              true,
+					ReadOnlyAttach:        true,
+					ControllerExpand:      true,
+					ListPublishedNodes:    true,
+					Condition:             true,
+					Get:                   true,
+					SingleNodeMultiWriter: true,
+				},
+				Snapshot: v1alpha1.ProvisionerCapabilitySpecFeaturesSnapshot{Create: true, List: true},
+			},
+		},
+		{
+			name:       "online expansion",
+			pluginCaps: []*csi.PluginCapability{newExpansionCapability(csi.PluginCapability_VolumeExpansion_ONLINE)},
+			controllerCaps: []csi.ControllerServiceCapability_RPC_Type{
+				csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+			},
+			expected: v1alpha1.ProvisionerCapabilitySpecFeatures{
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: v1alpha1.ExpandModeOnline, ControllerExpand: true},
+			},
+		},
+		{
+			name:       "offline expansion",
+			pluginCaps: []*csi.PluginCapability{newExpansionCapability(csi.PluginCapability_VolumeExpansion_OFFLINE)},
+			expected: v1alpha1.ProvisionerCapabilitySpecFeatures{
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: v1alpha1.ExpandModeOffline},
+			},
+		},
+		{
+			name:       "unknown expansion",
+			pluginCaps: []*csi.PluginCapability{newExpansionCapability(csi.PluginCapability_VolumeExpansion_UNKNOWN)},
+			expected: v1alpha1.ProvisionerCapabilitySpecFeatures{
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: v1alpha1.ExpandModeUnknown},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestPlugin(t,
+				&fakeIdentityServer{pluginCaps: test.pluginCaps},
+				&fakeControllerServer{controllerCaps: test.controllerCaps},
+				&fakeNodeServer{nodeCaps: test.nodeCaps})
+			spec, err := p.GetFullCapability()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedInfo := v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"}
+			if spec.PluginInfo != expectedInfo {
+				t.Errorf("expected plugin info %+v, got %+v", expectedInfo, spec.PluginInfo)
+			}
+			if !reflect.DeepEqual(spec.Features, test.expected) {
+				t.Errorf("unexpected features:\n%s", diff.ObjectGoPrintSideBySide(test.expected, spec.Features))
+			}
+		})
+	}
+}
+
+func TestGetFullCapabilityNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodeCaps []csi.NodeServiceCapability_RPC_Type
+		expected v1alpha1.ProvisionerCapabilitySpecFeaturesNode
+	}{
+		{
+			name: "no node capability",
+		},
+		{
+			name: "stage unstage and expand",
+			nodeCaps: []csi.NodeServiceCapability_RPC_Type{
+				csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+				csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+			},
+			expected: v1alpha1.ProvisionerCapabilitySpecFeaturesNode{StageUnstage: true, Expand: true},
+		},
+		{
+			name: "all node capabilities",
+			nodeCaps: []csi.NodeServiceCapability_RPC_Type{
+				csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+				csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+				csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+				csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+				csi.NodeServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
+			},
+			expected: v1alpha1.ProvisionerCapabilitySpecFeaturesNode{
+				StageUnstage:          true,
+				Expand:                true,
+				Condition:             true,
+				SingleNodeMultiWriter: true,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestPlugin(t, &fakeIdentityServer{}, &fakeControllerServer{}, &fakeNodeServer{nodeCaps: test.nodeCaps})
+			spec, err := p.GetFullCapability()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spec.Features.Node != test.expected {
+				t.Errorf("expected node features %+v, got %+v", test.expected, spec.Features.Node)
+			}
+		})
+	}
+}
+
+func TestGetNodeCapability(t *testing.T) {
+	pluginInfo := v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"}
+	tests := []struct {
+		name     string
+		nodeInfo *csi.NodeGetInfoResponse
+		nodeCaps []csi.NodeServiceCapability_RPC_Type
+		expected *v1alpha1.NodeCapabilitySpec
+	}{
+		{
+			name:     "without topology",
+			nodeInfo: &csi.NodeGetInfoResponse{NodeId: "id1", AccessibleTopology: &csi.Topology{}},
+			expected: &v1alpha1.NodeCapabilitySpec{NodeName: "node1", PluginInfo: pluginInfo, NodeID: "id1"},
+		},
+		{
+			name: "with topology and limit",
+			nodeInfo: &csi.NodeGetInfoResponse{
+				NodeId:             "id1",
+				MaxVolumesPerNode:  16,
+				AccessibleTopology: &csi.Topology{Segments: map[string]string{"zone": "a"}},
+			},
+			nodeCaps: []csi.NodeServiceCapability_RPC_Type{csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME},
+			expected: &v1alpha1.NodeCapabilitySpec{
+				NodeName:           "node1",
+				PluginInfo:         pluginInfo,
+				NodeID:             "id1",
+				MaxVolumesPerNode:  16,
+				AccessibleTopology: map[string]string{"zone": "a"},
+				Features:           v1alpha1.ProvisionerCapabilitySpecFeaturesNode{StageUnstage: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestPlugin(t, &fakeIdentityServer{}, &fakeControllerServer{},
+				&fakeNodeServer{nodeCaps: test.nodeCaps, nodeInfo: test.nodeInfo})
+			spec, err := p.GetNodeCapability("node1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(spec, test.expected) {
+				t.Errorf("unexpected node capability:\n%s", diff.ObjectGoPrintSideBySide(test.expected, spec))
+			}
+		})
+	}
+}
+
+func TestGetVolumeModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		confirm   func(volumeID string, volCap *csi.VolumeCapability) bool
+		expected  []v1alpha1.VolumeModeCapability
+		expectErr bool
+	}{
+		{
+			name:    "nothing confirmed",
+			confirm: func(string, *csi.VolumeCapability) bool { return false },
+		},
+		{
+			name:    "everything confirmed",
+			confirm: func(string, *csi.VolumeCapability) bool { return true },
+			expected: []v1alpha1.VolumeModeCapability{
+				{
+					VolumeMode:  corev1.PersistentVolumeFilesystem,
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany},
+				},
+				{
+					VolumeMode:  corev1.PersistentVolumeBlock,
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany},
+				},
+			},
+		},
+		{
+			name: "single writer filesystem and any block",
+			confirm: func(volumeID string, volCap *csi.VolumeCapability) bool {
+				if volumeID != "probe-volume" {
+					return false
+				}
+				if volCap.GetBlock() != nil {
+					return true
+				}
+				return volCap.GetMount() != nil && volCap.GetAccessMode().GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER
+			},
+			expected: []v1alpha1.VolumeModeCapability{
+				{
+					VolumeMode:  corev1.PersistentVolumeFilesystem,
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				},
+				{
+					VolumeMode:  corev1.PersistentVolumeBlock,
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany},
+				},
+			},
+		},
+		{
+			name:      "not implemented",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestPlugin(t, &fakeIdentityServer{}, &fakeControllerServer{confirm: test.confirm}, &fakeNodeServer{})
+			modes, err := p.GetVolumeModes("probe-volume")
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(modes, test.expected) {
+				t.Errorf("unexpected volume modes:\n%s", diff.ObjectGoPrintSideBySide(test.expected, modes))
+			}
+		})
+	}
+}
@@ -19,15 +19,20 @@
 package sidecar
 
 import (
+	"fmt"
 	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
 	clientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
 	informers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions/storagecapability/v1alpha1"
 	"github.com/kubesphere/storage-capability/pkg/handler"
+	pkgerrors "github.com/pkg/errors"
 	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog"
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -37,19 +42,31 @@ type csiSidecarController struct {
 	provisionerInformer informers.Interface
 	timeout             time.Duration
 	resyncPeriod        time.Duration
+	// driverName is the name reported by GetPluginInfo at startup or by the last successful probe.
+	// It is used to record probe failures in the ProvisionerCapability status.
+	driverName string
+	// volumeModes supplied by the operator take precedence over probing with probeVolumeID.
+	volumeModes   []v1alpha1.VolumeModeCapability
+	probeVolumeID string
 }
 
 func NewCSISidecarController(
 	clientSet clientset.Interface,
 	csiConn *grpc.ClientConn,
+	driverName string,
 	timeout time.Duration,
 	resyncPeriod time.Duration,
+	volumeModes []v1alpha1.VolumeModeCapability,
+	probeVolumeID string,
 ) *csiSidecarController {
 	return &csiSidecarController{
 		clientset:     clientSet,
 		pluginHandler: handler.NewPlugin(csiConn, timeout),
+		driverName:    driverName,
 		timeout:       timeout,
 		resyncPeriod:  resyncPeriod,
+		volumeModes:   volumeModes,
+		probeVolumeID: probeVolumeID,
 	}
 }
 
@@ -61,18 +78,108 @@ func (ctrl *csiSidecarController) Run(stopCh <-chan struct{}) {
 }
 
 func (ctrl *csiSidecarController) contentWorker() {
+	recordSync(syncKindProvisioner, retryOnForbidden(ctrl.syncProvisionerCapability))
+}
+
+// forbiddenBackoff waits about half a minute in total, which covers the reconciler of the webhook binding
+// the sidecar ClusterRole after the pod is created.
+var forbiddenBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Steps:    5,
+}
+
+// retryOnForbidden calls sync again with backoff while it is denied by the API server. The ClusterRoleBinding
+// of an injected sidecar is created asynchronously, so the first syncs of a new pod may be forbidden.
+func retryOnForbidden(sync func() error) error {
+	var err error
+	_ = wait.ExponentialBackoff(forbiddenBackoff, func() (bool, error) {
+		err = sync()
+		if errors.IsForbidden(pkgerrors.Cause(err)) {
+			klog.Warningf("Sync forbidden, waiting for the sidecar ClusterRoleBinding: %s", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	return err
+}
+
+// syncProvisionerCapability probes the CSI plugin and writes the result to ProvisionerCapability.
+func (ctrl *csiSidecarController) syncProvisionerCapability() error {
 	// Get Capability from plugin
 	pcapSpec, err := ctrl.pluginHandler.GetFullCapability()
 	if err != nil {
-		return
+		klog.Errorf("Get capability from CSI plugin error: %s", err)
+		if err := ctrl.updateProbeFailedStatus(err); err != nil {
+			klog.Errorf("Update provisioner CRD status error: %s", err)
+		}
+		return err
+	}
+	ctrl.driverName = pcapSpec.PluginInfo.Name
+	pcapSpec.Features.Volume.VolumeModes, err = ctrl.getVolumeModes()
+	if err != nil {
+		klog.Errorf("Get volume modes from CSI plugin error: %s", err)
+		if err := ctrl.updateProbeFailedStatus(err); err != nil {
+			klog.Errorf("Update provisioner CRD status error: %s", err)
+		}
+		return err
 	}
 	// Create or update Provisioner CRD
 	pcap, err := ctrl.createOrUpdateProvisionerCRD(pcapSpec)
 	if err != nil {
 		klog.Errorf("Create or update provisioner CRD error: %s", err)
-		return
+		return err
+	}
+	// Update Provisioner CRD status
+	pcap, err = ctrl.updateReadyStatus(pcap)
+	if err != nil {
+		klog.Errorf("Update provisioner CRD status error: %s", err)
+		return err
 	}
 	klog.V(5).Infof("Succeed to create or update CRD %v", pcap)
+	return nil
+}
+
+// getVolumeModes returns the volume modes supplied by the operator, or probes them with the probe volume.
+// Nothing is returned if neither is configured.
+func (ctrl *csiSidecarController) getVolumeModes() ([]v1alpha1.VolumeModeCapability, error) {
+	if ctrl.volumeModes != nil {
+		return ctrl.volumeModes, nil
+	}
+	if ctrl.probeVolumeID == "" {
+		return nil, nil
+	}
+	return ctrl.pluginHandler.GetVolumeModes(ctrl.probeVolumeID)
+}
+
+// ParseVolumeModes parses volume modes in the form of "Filesystem=ReadWriteOnce,ReadWriteMany;Block=ReadWriteOnce".
+func ParseVolumeModes(s string) ([]v1alpha1.VolumeModeCapability, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var res []v1alpha1.VolumeModeCapability
+	for _, item := range strings.Split(s, ";") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid volume mode %q, expect <volumeMode>=<accessMode>[,<accessMode>]", item)
+		}
+		volumeMode := corev1.PersistentVolumeMode(strings.TrimSpace(parts[0]))
+		if volumeMode != corev1.PersistentVolumeFilesystem && volumeMode != corev1.PersistentVolumeBlock {
+			return nil, fmt.Errorf("unknown volume mode %q", volumeMode)
+		}
+		modeCap := v1alpha1.VolumeModeCapability{VolumeMode: volumeMode}
+		for _, mode := range strings.Split(parts[1], ",") {
+			accessMode := corev1.PersistentVolumeAccessMode(strings.TrimSpace(mode))
+			switch accessMode {
+			case corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany:
+				modeCap.AccessModes = append(modeCap.AccessModes, accessMode)
+			default:
+				return nil, fmt.Errorf("unknown access mode %q", accessMode)
+			}
+		}
+		res = append(res, modeCap)
+	}
+	return res, nil
 }
 
 func (ctrl *csiSidecarController) createOrUpdateProvisionerCRD(pcapSpec *v1alpha1.ProvisionerCapabilitySpec) (*v1alpha1.ProvisionerCapability, error) {
@@ -86,15 +193,15 @@ func (ctrl *csiSidecarController) createOrUpdateProvisionerCRD(pcapSpec *v1alpha
 	if err != nil {
 		klog.Errorf("Get provisioner CRD error: %s", err)
 	}
-	if pcap.GetName() == pcapSpec.PluginInfo.Name {
+	if err == nil && pcap.GetName() == pcapSpec.PluginInfo.Name {
 		// Need to update CRD
-		if !reflect.DeepEqual(pcap.Spec, pcapSpec) {
+		if !reflect.DeepEqual(pcap.Spec, *pcapSpec) {
 			klog.V(0).Infof("Update CRD")
 			pcap.Spec = *pcapSpec
 			return ctrl.clientset.StorageV1alpha1().ProvisionerCapabilities().Update(pcap)
 		} else {
 			klog.V(0).Infof("CRD is equal to current status, nothing to update")
-			return nil, nil
+			return pcap, nil
 		}
 	} else {
 		// Need to create CRD
@@ -108,3 +215,78 @@ func (ctrl *csiSidecarController) createOrUpdateProvisionerCRD(pcapSpec *v1alpha
 			})
 	}
 }
+
+// updateReadyStatus records a successful probe in the status of ProvisionerCapability.
+func (ctrl *csiSidecarController) updateReadyStatus(pcap *v1alpha1.ProvisionerCapability) (*v1alpha1.ProvisionerCapability, error) {
+	if pcap == nil {
+		return nil, nil
+	}
+	now := v1.Now()
+	res := pcap.DeepCopy()
+	res.Status.ObservedGeneration = pcap.GetGeneration()
+	res.Status.LastProbeTime = &now
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityReady,
+		Status:             v1.ConditionTrue,
+		ObservedGeneration: pcap.GetGeneration(),
+		Reason:             "ProbeSucceeded",
+		Message:            "CSI plugin capabilities probed successfully",
+	})
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityProbeFailed,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: pcap.GetGeneration(),
+		Reason:             "ProbeSucceeded",
+	})
+	return ctrl.clientset.StorageV1alpha1().ProvisionerCapabilities().UpdateStatus(res)
+}
+
+// updateProbeFailedStatus records a failed probe in the status of ProvisionerCapability. A ProvisionerCapability
+// without features is created if the driver has never been probed successfully, so the failure is visible.
+// Nothing is recorded if the name of the driver is unknown.
+func (ctrl *csiSidecarController) updateProbeFailedStatus(probeErr error) error {
+	if ctrl.driverName == "" {
+		return nil
+	}
+	pcap, err := ctrl.clientset.StorageV1alpha1().ProvisionerCapabilities().Get(ctrl.driverName, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		klog.V(0).Infof("Create CRD %s without features", ctrl.driverName)
+		pcap, err = ctrl.clientset.StorageV1alpha1().ProvisionerCapabilities().Create(
+			&v1alpha1.ProvisionerCapability{
+				ObjectMeta: v1.ObjectMeta{
+					Name: ctrl.driverName,
+				},
+				Spec: v1alpha1.ProvisionerCapabilitySpec{
+					PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: ctrl.driverName},
+					// expandMode is required by the CRD schema.
+					Features: v1alpha1.ProvisionerCapabilitySpecFeatures{
+						Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: v1alpha1.ExpandModeUnknown},
+					},
+				},
+			})
+	}
+	if err != nil {
+		return err
+	}
+	res := pcap.DeepCopy()
+	res.Status.ObservedGeneration = pcap.GetGeneration()
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: pcap.GetGeneration(),
+		Reason:             "ProbeFailed",
+		Message:            "The last probe of the CSI plugin failed",
+	})
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityProbeFailed,
+		Status:             v1.ConditionTrue,
+		ObservedGeneration: pcap.GetGeneration(),
+		Reason:             "ProbeFailed",
+		Message:            probeErr.Error(),
+	})
+	if reflect.DeepEqual(pcap.Status, res.Status) {
+		return nil
+	}
+	_, err = ctrl.clientset.StorageV1alpha1().ProvisionerCapabilities().UpdateStatus(res)
+	return err
+}
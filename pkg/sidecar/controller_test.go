@@ -0,0 +1,664 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package sidecar
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	crdfake "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/fake"
+	"github.com/kubesphere/storage-capability/pkg/webhook"
+	pkgerrors "github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDriver = "csi.example.com"
+
+// fakePluginHandler returns the given capabilities, or the error if set.
+type fakePluginHandler struct {
+	pcapSpec    *v1alpha1.ProvisionerCapabilitySpec
+	ncapSpec    *v1alpha1.NodeCapabilitySpec
+	volumeModes []v1alpha1.VolumeModeCapability
+	err         error
+	// probing, if set, receives once GetNodeCapability is called, which then waits for release.
+	probing chan struct{}
+	release chan struct{}
+}
+
+func (h *fakePluginHandler) GetFullCapability() (*v1alpha1.ProvisionerCapabilitySpec, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	return h.pcapSpec.DeepCopy(), nil
+}
+
+func (h *fakePluginHandler) GetNodeCapability(nodeName string) (*v1alpha1.NodeCapabilitySpec, error) {
+	if h.probing != nil {
+		h.probing <- struct{}{}
+		<-h.release
+	}
+	if h.err != nil {
+		return nil, h.err
+	}
+	spec := h.ncapSpec.DeepCopy()
+	spec.NodeName = nodeName
+	return spec, nil
+}
+
+func (h *fakePluginHandler) GetVolumeModes(probeVolumeID string) ([]v1alpha1.VolumeModeCapability, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	return h.volumeModes, nil
+}
+
+func newTestSidecarController(client *crdfake.Clientset, h *fakePluginHandler, driverName string) *csiSidecarController {
+	return &csiSidecarController{
+		clientset:     client,
+		pluginHandler: h,
+		driverName:    driverName,
+		timeout:       time.Second,
+		resyncPeriod:  time.Minute,
+	}
+}
+
+func checkCondition(t *testing.T, status v1alpha1.CapabilityStatus, conditionType v1alpha1.CapabilityConditionType, expected v1.ConditionStatus) {
+	t.Helper()
+	cond := status.FindCondition(conditionType)
+	if cond == nil {
+		t.Errorf("condition %s not found", conditionType)
+		return
+	}
+	if cond.Status != expected {
+		t.Errorf("expected condition %s to be %s, got %s", conditionType, expected, cond.Status)
+	}
+}
+
+func TestProbeFailedAtStartup(t *testing.T) {
+	client := crdfake.NewSimpleClientset()
+	h := &fakePluginHandler{err: errors.New("connection refused")}
+	ctrl := newTestSidecarController(client, h, testDriver)
+	if err := ctrl.syncProvisionerCapability(); err == nil {
+		t.Fatal("expected probe error")
+	}
+	pcap, err := client.StorageV1alpha1().ProvisionerCapabilities().Get(testDriver, v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("ProvisionerCapability of the failed probe not created: %v", err)
+	}
+	if pcap.Spec.PluginInfo.Name != testDriver {
+		t.Errorf("expected plugin name %s, got %s", testDriver, pcap.Spec.PluginInfo.Name)
+	}
+	checkCondition(t, pcap.Status, v1alpha1.CapabilityProbeFailed, v1.ConditionTrue)
+	checkCondition(t, pcap.Status, v1alpha1.CapabilityReady, v1.ConditionFalse)
+	// The fake clientset does not validate, the webhook rejects what the CRD schema rejects.
+	validatePcap(t, pcap)
+
+	// The next successful probe fills the features and clears the failure.
+	h.err = nil
+	h.pcapSpec = &v1alpha1.ProvisionerCapabilitySpec{
+		PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"},
+		Features: v1alpha1.ProvisionerCapabilitySpecFeatures{
+			Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Create: true},
+		},
+	}
+	if err := ctrl.syncProvisionerCapability(); err != nil {
+		t.Fatal(err)
+	}
+	pcap, err = client.StorageV1alpha1().ProvisionerCapabilities().Get(testDriver, v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pcap.Spec.Features.Volume.Create {
+		t.Error("expected features of the successful probe")
+	}
+	checkCondition(t, pcap.Status, v1alpha1.CapabilityProbeFailed, v1.ConditionFalse)
+	checkCondition(t, pcap.Status, v1alpha1.CapabilityReady, v1.ConditionTrue)
+}
+
+// validatePcap checks the ProvisionerCapability with the validating webhook.
+func validatePcap(t *testing.T, pcap *v1alpha1.ProvisionerCapability) {
+	obj := pcap.DeepCopy()
+	obj.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("ProvisionerCapability"))
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &admissionv1.AdmissionRequest{
+		Resource:  v1.GroupVersionResource{Group: v1alpha1.SchemeGroupVersion.Group, Version: v1alpha1.SchemeGroupVersion.Version, Resource: "provisionercapabilities"},
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+	if _, err := webhook.NewCapabilityValidator(nil).Validate(req, nil); err != nil {
+		t.Errorf("expected valid ProvisionerCapability, got %v", err)
+	}
+}
+
+func TestProbeFailedWithoutDriverName(t *testing.T) {
+	client := crdfake.NewSimpleClientset()
+	ctrl := newTestSidecarController(client, &fakePluginHandler{err: errors.New("connection refused")}, "")
+	if err := ctrl.syncProvisionerCapability(); err == nil {
+		t.Fatal("expected probe error")
+	}
+	list, err := client.StorageV1alpha1().ProvisionerCapabilities().List(v1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 0 {
+		t.Errorf("expected no ProvisionerCapability without driver name, got %d", len(list.Items))
+	}
+}
+
+const testNode = "node1"
+
+func newTestNodeSidecarController(client *crdfake.Clientset, h *fakePluginHandler) *csiNodeSidecarController {
+	node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: testNode, UID: "node-uid"}}
+	return &csiNodeSidecarController{
+		clientset:     client,
+		kubeClient:    kubefake.NewSimpleClientset(node),
+		pluginHandler: h,
+		nodeName:      testNode,
+		timeout:       time.Second,
+		resyncPeriod:  time.Minute,
+	}
+}
+
+func TestSyncNodeCapability(t *testing.T) {
+	name := NodeCapabilityName(testNode, testDriver)
+	tests := []struct {
+		name     string
+		existing *v1alpha1.NodeCapability
+	}{
+		{
+			name: "create",
+		},
+		{
+			name: "add owner to existing",
+			existing: &v1alpha1.NodeCapability{
+				ObjectMeta: v1.ObjectMeta{Name: name},
+				Spec: v1alpha1.NodeCapabilitySpec{
+					NodeName:   testNode,
+					PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"},
+					NodeID:     "id1",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := crdfake.NewSimpleClientset()
+			if test.existing != nil {
+				client = crdfake.NewSimpleClientset(test.existing)
+			}
+			h := &fakePluginHandler{ncapSpec: &v1alpha1.NodeCapabilitySpec{
+				PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"},
+				NodeID:     "id1",
+				Features:   v1alpha1.ProvisionerCapabilitySpecFeaturesNode{StageUnstage: true},
+			}}
+			ctrl := newTestNodeSidecarController(client, h)
+			if err := ctrl.syncNodeCapability(); err != nil {
+				t.Fatal(err)
+			}
+			ncap, err := client.StorageV1alpha1().NodeCapabilities().Get(name, v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ncap.Spec.Features.StageUnstage || ncap.Spec.NodeName != testNode {
+				t.Errorf("unexpected spec %+v", ncap.Spec)
+			}
+			refs := ncap.GetOwnerReferences()
+			if len(refs) != 1 || refs[0].Kind != "Node" || refs[0].Name != testNode || refs[0].UID != "node-uid" {
+				t.Errorf("expected the node as owner, got %+v", refs)
+			}
+			checkCondition(t, ncap.Status, v1alpha1.CapabilityReady, v1.ConditionTrue)
+
+			// A failed probe is recorded on the known NodeCapability.
+			h.err = errors.New("connection refused")
+			if err := ctrl.syncNodeCapability(); err == nil {
+				t.Fatal("expected probe error")
+			}
+			ncap, err = client.StorageV1alpha1().NodeCapabilities().Get(name, v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkCondition(t, ncap.Status, v1alpha1.CapabilityReady, v1.ConditionFalse)
+			checkCondition(t, ncap.Status, v1alpha1.CapabilityProbeFailed, v1.ConditionTrue)
+		})
+	}
+}
+
+func TestNodeSidecarCleanupOnExit(t *testing.T) {
+	tests := []struct {
+		name          string
+		cleanupOnExit bool
+		expectCount   int
+	}{
+		{
+			name:        "kept on exit by default",
+			expectCount: 1,
+		},
+		{
+			name:          "deleted on exit",
+			cleanupOnExit: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := crdfake.NewSimpleClientset()
+			h := &fakePluginHandler{ncapSpec: &v1alpha1.NodeCapabilitySpec{
+				PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"},
+			}}
+			ctrl := newTestNodeSidecarController(client, h)
+			ctrl.cleanupOnExit = test.cleanupOnExit
+			if err := ctrl.syncNodeCapability(); err != nil {
+				t.Fatal(err)
+			}
+			stopCh := make(chan struct{})
+			close(stopCh)
+			ctrl.Run(stopCh)
+			list, err := client.StorageV1alpha1().NodeCapabilities().List(v1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Items) != test.expectCount {
+				t.Errorf("expected %d NodeCapability after exit, got %d", test.expectCount, len(list.Items))
+			}
+		})
+	}
+}
+
+func TestNodeSidecarCleanupAfterSync(t *testing.T) {
+	client := crdfake.NewSimpleClientset()
+	h := &fakePluginHandler{
+		ncapSpec: &v1alpha1.NodeCapabilitySpec{
+			PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"},
+		},
+		probing: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	ctrl := newTestNodeSidecarController(client, h)
+	ctrl.cleanupOnExit = true
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(stopCh)
+		close(done)
+	}()
+	// Stop while a sync is in flight, the sync creates the NodeCapability after the stop.
+	<-h.probing
+	close(stopCh)
+	// Give Run the time to delete the NodeCapability if it did not wait for the sync.
+	time.Sleep(50 * time.Millisecond)
+	close(h.release)
+	<-done
+	var verbs []string
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" || action.GetVerb() == "delete" {
+			verbs = append(verbs, action.GetVerb())
+		}
+	}
+	if !reflect.DeepEqual(verbs, []string{"create", "delete"}) {
+		t.Errorf("expected the NodeCapability to be deleted after the last sync created it, got %v", verbs)
+	}
+}
+
+func TestParseVolumeModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expected  []v1alpha1.VolumeModeCapability
+		expectErr bool
+	}{
+		{
+			name:  "empty",
+			value: " ",
+		},
+		{
+			name:  "filesystem and block",
+			value: "Filesystem=ReadWriteOnce, ReadWriteMany;Block=ReadWriteOnce",
+			expected: []v1alpha1.VolumeModeCapability{
+				{
+					VolumeMode:  corev1.PersistentVolumeFilesystem,
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce, corev1.ReadWriteMany},
+				},
+				{
+					VolumeMode:  corev1.PersistentVolumeBlock,
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				},
+			},
+		},
+		{
+			name:      "missing access modes",
+			value:     "Filesystem",
+			expectErr: true,
+		},
+		{
+			name:      "unknown volume mode",
+			value:     "Raw=ReadWriteOnce",
+			expectErr: true,
+		},
+		{
+			name:      "unknown access mode",
+			value:     "Block=ReadWriteOncePod",
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modes, err := ParseVolumeModes(test.value)
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(modes, test.expected) {
+				t.Errorf("unexpected volume modes:\n%s", diff.ObjectGoPrintSideBySide(test.expected, modes))
+			}
+		})
+	}
+}
+
+func TestSyncVolumeModes(t *testing.T) {
+	configured := []v1alpha1.VolumeModeCapability{
+		{VolumeMode: corev1.PersistentVolumeBlock, AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+	}
+	probed := []v1alpha1.VolumeModeCapability{
+		{VolumeMode: corev1.PersistentVolumeFilesystem, AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}},
+	}
+	tests := []struct {
+		name          string
+		volumeModes   []v1alpha1.VolumeModeCapability
+		probeVolumeID string
+		expected      []v1alpha1.VolumeModeCapability
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name:          "probed",
+			probeVolumeID: "probe-volume",
+			expected:      probed,
+		},
+		{
+			name:          "configured overrides probe",
+			volumeModes:   configured,
+			probeVolumeID: "probe-volume",
+			expected:      configured,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := crdfake.NewSimpleClientset()
+			h := &fakePluginHandler{
+				pcapSpec: &v1alpha1.ProvisionerCapabilitySpec{
+					PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: testDriver, Version: "v1.0.0"},
+				},
+				volumeModes: probed,
+			}
+			ctrl := newTestSidecarController(client, h, testDriver)
+			ctrl.volumeModes = test.volumeModes
+			ctrl.probeVolumeID = test.probeVolumeID
+			if err := ctrl.syncProvisionerCapability(); err != nil {
+				t.Fatal(err)
+			}
+			pcap, err := client.StorageV1alpha1().ProvisionerCapabilities().Get(testDriver, v1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(pcap.Spec.Features.Volume.VolumeModes, test.expected) {
+				t.Errorf("unexpected volume modes:\n%s", diff.ObjectGoPrintSideBySide(test.expected, pcap.Spec.Features.Volume.VolumeModes))
+			}
+		})
+	}
+}
+
+func TestRecordSync(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	RegisterMetrics(registry)
+	syncTotal.Reset()
+	lastSuccessfulSync.Reset()
+
+	before := time.Now().Unix()
+	recordSync(syncKindProvisioner, nil)
+	recordSync(syncKindProvisioner, errors.New("connection refused"))
+	recordSync(syncKindNode, errors.New("connection refused"))
+	after := time.Now().Unix()
+
+	expected := `
+# HELP storage_capability_sidecar_sync_total [ALPHA] Number of probes of the CSI plugin written to ProvisionerCapability or NodeCapability, per kind and result.
+# TYPE storage_capability_sidecar_sync_total counter
+storage_capability_sidecar_sync_total{kind="node",result="error"} 1
+storage_capability_sidecar_sync_total{kind="provisioner",result="error"} 1
+storage_capability_sidecar_sync_total{kind="provisioner",result="success"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "storage_capability_sidecar_sync_total"); err != nil {
+		t.Error(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples int
+	for _, family := range families {
+		if family.GetName() != "storage_capability_sidecar_last_successful_sync_timestamp_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			samples++
+			if kind := m.GetLabel()[0].GetValue(); kind != syncKindProvisioner {
+				t.Errorf("unexpected successful sync of kind %s", kind)
+			}
+			if value := int64(m.GetGauge().GetValue()); value < before || value > after {
+				t.Errorf("expected last successful sync between %d and %d, got %d", before, after, value)
+			}
+		}
+	}
+	if samples != 1 {
+		t.Errorf("expected 1 last successful sync, got %d", samples)
+	}
+}
+
+func TestRetryOnForbidden(t *testing.T) {
+	defer func(backoff wait.Backoff) { forbiddenBackoff = backoff }(forbiddenBackoff)
+	forbiddenBackoff = wait.Backoff{Duration: time.Millisecond, Steps: 3}
+	forbidden := k8serrors.NewForbidden(schema.GroupResource{Resource: "nodes"}, "node1", errors.New("no binding"))
+	tests := []struct {
+		name        string
+		errs        []error
+		expectCalls int
+		expectErr   bool
+	}{
+		{
+			name:        "success",
+			errs:        []error{nil},
+			expectCalls: 1,
+		},
+		{
+			name:        "other error is not retried",
+			errs:        []error{errors.New("probe failed")},
+			expectCalls: 1,
+			expectErr:   true,
+		},
+		{
+			name:        "forbidden until bound",
+			errs:        []error{forbidden, pkgerrors.Wrap(forbidden, "get node node1 error"), nil},
+			expectCalls: 3,
+		},
+		{
+			name:        "forbidden after backoff",
+			errs:        []error{forbidden, forbidden, forbidden},
+			expectCalls: 3,
+			expectErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			err := retryOnForbidden(func() error {
+				err := test.errs[calls]
+				calls++
+				return err
+			})
+			if calls != test.expectCalls {
+				t.Errorf("expected %d calls, got %d", test.expectCalls, calls)
+			}
+			if (err != nil) != test.expectErr {
+				t.Errorf("expected error %v, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+// fakeRunner closes started when it runs.
+type fakeRunner struct {
+	started chan struct{}
+}
+
+func (r *fakeRunner) Run(stopCh <-chan struct{}) {
+	close(r.started)
+	<-stopCh
+}
+
+func TestLeaseName(t *testing.T) {
+	if name := LeaseName(testDriver); name != "storage-capability-csi.example.com" {
+		t.Errorf("expected Lease storage-capability-csi.example.com, got %s", name)
+	}
+	if LeaseName(testDriver) == LeaseName("csi.other.com") {
+		t.Error("expected different Leases for different drivers")
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	tests := []struct {
+		name         string
+		mode         string
+		enabled      bool
+		expectLeader bool
+	}{
+		{
+			name: "controller without leader election",
+			mode: ModeController,
+		},
+		{
+			name:         "controller with leader election",
+			mode:         ModeController,
+			enabled:      true,
+			expectLeader: true,
+		},
+		{
+			name:    "node ignores leader election",
+			mode:    ModeNode,
+			enabled: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := &fakeRunner{}
+			runner := NewRunner(ctrl, test.mode, testDriver, LeaderElectionConfig{
+				Enabled:    test.enabled,
+				KubeClient: kubeClient,
+				Namespace:  "kube-system",
+			})
+			leader, ok := runner.(*leaderElectionRunner)
+			if ok != test.expectLeader {
+				t.Fatalf("expected leader election %v, got runner %T", test.expectLeader, runner)
+			}
+			if !ok {
+				if runner != Runner(ctrl) {
+					t.Errorf("expected the controller to run directly, got %T", runner)
+				}
+				return
+			}
+			if leader.ctrl != Runner(ctrl) || leader.leaseName != LeaseName(testDriver) || leader.namespace != "kube-system" {
+				t.Errorf("unexpected leader election runner %+v", leader)
+			}
+		})
+	}
+}
+
+func TestLeaderElectionRunner(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	ctrl := &fakeRunner{started: make(chan struct{})}
+	runner := NewRunner(ctrl, ModeController, testDriver, LeaderElectionConfig{
+		Enabled:    true,
+		KubeClient: kubeClient,
+		Namespace:  "kube-system",
+	})
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		runner.Run(stopCh)
+		close(done)
+	}()
+	select {
+	case <-ctrl.started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("controller not started after acquiring the Lease")
+	}
+	// The Lease name is sanitized by the leader election library.
+	if _, err := kubeClient.CoordinationV1().Leases("kube-system").Get("storage-capability-csi-example-com", v1.GetOptions{}); err != nil {
+		t.Errorf("expected Lease of the driver, got %v", err)
+	}
+	close(stopCh)
+	<-done
+}
+
+func TestWaitForDriverName(t *testing.T) {
+	calls := 0
+	name, err := WaitForDriverName(func() (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errors.New("connection refused")
+		}
+		return testDriver, nil
+	}, time.Millisecond, 2*time.Millisecond, make(chan struct{}))
+	if err != nil || name != testDriver {
+		t.Errorf("expected driver name %s, got %q %v", testDriver, name, err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+
+	stopCh := make(chan struct{})
+	close(stopCh)
+	if _, err := WaitForDriverName(func() (string, error) {
+		return "", errors.New("connection refused")
+	}, time.Hour, time.Hour, stopCh); err == nil {
+		t.Error("expected error after stop")
+	}
+}
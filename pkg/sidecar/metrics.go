@@ -0,0 +1,70 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package sidecar
+
+import (
+	"k8s.io/component-base/metrics"
+	"time"
+)
+
+const (
+	metricsNamespace = "storage_capability"
+	metricsSubsystem = "sidecar"
+
+	syncKindProvisioner = "provisioner"
+	syncKindNode        = "node"
+)
+
+var (
+	syncTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "sync_total",
+			Help:           "Number of probes of the CSI plugin written to ProvisionerCapability or NodeCapability, per kind and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"kind", "result"},
+	)
+	lastSuccessfulSync = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "last_successful_sync_timestamp_seconds",
+			Help:           "Unix time of the last successful sync of ProvisionerCapability or NodeCapability.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"kind"},
+	)
+)
+
+// RegisterMetrics registers the sidecar metrics to the registry. Per-RPC metrics of the CSI plugin are
+// recorded by the CSIMetricsManager of the connection, so the registry of the manager should be used.
+func RegisterMetrics(registry metrics.KubeRegistry) {
+	registry.MustRegister(syncTotal, lastSuccessfulSync)
+}
+
+func recordSync(kind string, err error) {
+	if err != nil {
+		syncTotal.WithLabelValues(kind, "error").Inc()
+		return
+	}
+	syncTotal.WithLabelValues(kind, "success").Inc()
+	lastSuccessfulSync.WithLabelValues(kind).Set(float64(time.Now().Unix()))
+}
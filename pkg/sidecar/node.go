@@ -0,0 +1,249 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package sidecar
+
+import (
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	clientset "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned"
+	"github.com/kubesphere/storage-capability/pkg/handler"
+	pkgerrors "github.com/pkg/errors"
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"reflect"
+	"time"
+)
+
+// csiNodeSidecarController runs next to a CSI node plugin, usually in a DaemonSet,
+// and records the capability of the plugin on the local node in a NodeCapability.
+// The NodeCapability is owned by the Node, so it is garbage collected together with the Node.
+type csiNodeSidecarController struct {
+	clientset     clientset.Interface
+	kubeClient    kubernetes.Interface
+	pluginHandler handler.PluginHandler
+	nodeName      string
+	timeout       time.Duration
+	resyncPeriod  time.Duration
+	// cleanupOnExit deletes the NodeCapability when the sidecar stops. It is meant for uninstalling the node plugin,
+	// otherwise the Node owner reference garbage collects the NodeCapability.
+	cleanupOnExit bool
+	// ncapName is the name of NodeCapability written by the last successful probe.
+	ncapName string
+}
+
+func NewCSINodeSidecarController(
+	clientSet clientset.Interface,
+	kubeClient kubernetes.Interface,
+	csiConn *grpc.ClientConn,
+	nodeName string,
+	timeout time.Duration,
+	resyncPeriod time.Duration,
+	cleanupOnExit bool,
+) *csiNodeSidecarController {
+	return &csiNodeSidecarController{
+		clientset:     clientSet,
+		kubeClient:    kubeClient,
+		pluginHandler: handler.NewPlugin(csiConn, timeout),
+		nodeName:      nodeName,
+		timeout:       timeout,
+		resyncPeriod:  resyncPeriod,
+		cleanupOnExit: cleanupOnExit,
+	}
+}
+
+// NodeCapabilityName returns the name of NodeCapability of the driver on the node.
+func NodeCapabilityName(nodeName, driverName string) string {
+	return nodeName + "." + driverName
+}
+
+func (ctrl *csiNodeSidecarController) Run(stopCh <-chan struct{}) {
+	klog.V(0).Infof("Starting node sidecar controller on node %s", ctrl.nodeName)
+	defer klog.V(0).Info("Shutting node sidecar controller")
+	workerDone := make(chan struct{})
+	go func() {
+		wait.Until(ctrl.nodeWorker, ctrl.resyncPeriod, stopCh)
+		close(workerDone)
+	}()
+	<-stopCh
+	// A sync in flight would recreate the NodeCapability right after it is deleted.
+	<-workerDone
+	if ctrl.cleanupOnExit {
+		if err := ctrl.deleteNodeCRD(); err != nil {
+			klog.Errorf("Delete node CRD error: %s", err)
+		}
+	}
+}
+
+func (ctrl *csiNodeSidecarController) nodeWorker() {
+	recordSync(syncKindNode, retryOnForbidden(ctrl.syncNodeCapability))
+}
+
+// syncNodeCapability probes the CSI node plugin and writes the result to NodeCapability.
+func (ctrl *csiNodeSidecarController) syncNodeCapability() error {
+	ncapSpec, err := ctrl.pluginHandler.GetNodeCapability(ctrl.nodeName)
+	if err != nil {
+		klog.Errorf("Get node capability from CSI plugin error: %s", err)
+		if err := ctrl.updateProbeFailedStatus(err); err != nil {
+			klog.Errorf("Update node CRD status error: %s", err)
+		}
+		return err
+	}
+	ctrl.ncapName = NodeCapabilityName(ctrl.nodeName, ncapSpec.PluginInfo.Name)
+	ncap, err := ctrl.createOrUpdateNodeCRD(ncapSpec)
+	if err != nil {
+		klog.Errorf("Create or update node CRD error: %s", err)
+		return err
+	}
+	ncap, err = ctrl.updateReadyStatus(ncap)
+	if err != nil {
+		klog.Errorf("Update node CRD status error: %s", err)
+		return err
+	}
+	klog.V(5).Infof("Succeed to create or update CRD %v", ncap)
+	return nil
+}
+
+func (ctrl *csiNodeSidecarController) createOrUpdateNodeCRD(ncapSpec *v1alpha1.NodeCapabilitySpec) (*v1alpha1.NodeCapability, error) {
+	node, err := ctrl.kubeClient.CoreV1().Nodes().Get(ncapSpec.NodeName, v1.GetOptions{})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "get node %s error", ncapSpec.NodeName)
+	}
+	ownerRef := nodeOwnerReference(node)
+	name := NodeCapabilityName(ncapSpec.NodeName, ncapSpec.PluginInfo.Name)
+	ncap, err := ctrl.clientset.StorageV1alpha1().NodeCapabilities().Get(name, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		klog.V(0).Infof("Create node CRD %s", name)
+		return ctrl.clientset.StorageV1alpha1().NodeCapabilities().Create(
+			&v1alpha1.NodeCapability{
+				ObjectMeta: v1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: []v1.OwnerReference{ownerRef},
+				},
+				Spec: *ncapSpec,
+			})
+	} else if err != nil {
+		return nil, err
+	}
+	hasOwner := hasOwnerReference(ncap.GetOwnerReferences(), node.GetUID())
+	if hasOwner && reflect.DeepEqual(ncap.Spec, *ncapSpec) {
+		klog.V(4).Infof("Node CRD %s is equal to current status, nothing to update", name)
+		return ncap, nil
+	}
+	klog.V(0).Infof("Update node CRD %s", name)
+	res := ncap.DeepCopy()
+	res.Spec = *ncapSpec
+	if !hasOwner {
+		// NodeCapabilities created by earlier versions have no owner.
+		res.OwnerReferences = append(res.OwnerReferences, ownerRef)
+	}
+	return ctrl.clientset.StorageV1alpha1().NodeCapabilities().Update(res)
+}
+
+// nodeOwnerReference returns the reference to the node owning its NodeCapabilities. It does not block
+// the deletion of the node, which would require the sidecar to be allowed to delete nodes.
+func nodeOwnerReference(node *corev1.Node) v1.OwnerReference {
+	return v1.OwnerReference{
+		APIVersion: corev1.SchemeGroupVersion.String(),
+		Kind:       "Node",
+		Name:       node.GetName(),
+		UID:        node.GetUID(),
+	}
+}
+
+func hasOwnerReference(refs []v1.OwnerReference, uid types.UID) bool {
+	for _, ref := range refs {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteNodeCRD deletes the NodeCapability written by the last successful probe.
+func (ctrl *csiNodeSidecarController) deleteNodeCRD() error {
+	if ctrl.ncapName == "" {
+		return nil
+	}
+	klog.V(0).Infof("Delete node CRD %s", ctrl.ncapName)
+	err := ctrl.clientset.StorageV1alpha1().NodeCapabilities().Delete(ctrl.ncapName, &v1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// updateReadyStatus records a successful probe in the status of NodeCapability.
+func (ctrl *csiNodeSidecarController) updateReadyStatus(ncap *v1alpha1.NodeCapability) (*v1alpha1.NodeCapability, error) {
+	now := v1.Now()
+	res := ncap.DeepCopy()
+	res.Status.ObservedGeneration = ncap.GetGeneration()
+	res.Status.LastProbeTime = &now
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityReady,
+		Status:             v1.ConditionTrue,
+		ObservedGeneration: ncap.GetGeneration(),
+		Reason:             "ProbeSucceeded",
+		Message:            "CSI node plugin capabilities probed successfully",
+	})
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityProbeFailed,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: ncap.GetGeneration(),
+		Reason:             "ProbeSucceeded",
+	})
+	return ctrl.clientset.StorageV1alpha1().NodeCapabilities().UpdateStatus(res)
+}
+
+// updateProbeFailedStatus records a failed probe in the status of NodeCapability.
+// Nothing is recorded if the node plugin has never been probed successfully, because the name is unknown.
+func (ctrl *csiNodeSidecarController) updateProbeFailedStatus(probeErr error) error {
+	if ctrl.ncapName == "" {
+		return nil
+	}
+	ncap, err := ctrl.clientset.StorageV1alpha1().NodeCapabilities().Get(ctrl.ncapName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	res := ncap.DeepCopy()
+	res.Status.ObservedGeneration = ncap.GetGeneration()
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityReady,
+		Status:             v1.ConditionFalse,
+		ObservedGeneration: ncap.GetGeneration(),
+		Reason:             "ProbeFailed",
+		Message:            "The last probe of the CSI node plugin failed",
+	})
+	res.Status.SetCondition(v1alpha1.CapabilityCondition{
+		Type:               v1alpha1.CapabilityProbeFailed,
+		Status:             v1.ConditionTrue,
+		ObservedGeneration: ncap.GetGeneration(),
+		Reason:             "ProbeFailed",
+		Message:            probeErr.Error(),
+	})
+	if reflect.DeepEqual(ncap.Status, res.Status) {
+		return nil
+	}
+	_, err = ctrl.clientset.StorageV1alpha1().NodeCapabilities().UpdateStatus(res)
+	return err
+}
@@ -0,0 +1,113 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package sidecar
+
+import (
+	"context"
+	"github.com/kubernetes-csi/csi-lib-utils/leaderelection"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	// ModeController runs the sidecar next to a CSI controller plugin and records a ProvisionerCapability.
+	ModeController = "controller"
+	// ModeNode runs the sidecar next to a CSI node plugin and records a NodeCapability.
+	ModeNode = "node"
+)
+
+// Runner runs a sidecar controller until stopCh is closed.
+type Runner interface {
+	Run(stopCh <-chan struct{})
+}
+
+// LeaderElectionConfig configures the leader election of controller sidecars.
+type LeaderElectionConfig struct {
+	Enabled    bool
+	KubeClient kubernetes.Interface
+	// Namespace of the Lease, the namespace of the pod if empty.
+	Namespace string
+}
+
+// LeaseName returns the name of the Lease shared by the controller sidecars of the driver. Replicas of different
+// drivers do not block each other.
+func LeaseName(driverName string) string {
+	return "storage-capability-" + driverName
+}
+
+// NewRunner returns the runner of the sidecar controller in the mode. Only controller sidecars elect a leader,
+// every node sidecar records the NodeCapability of its own node.
+func NewRunner(ctrl Runner, mode string, driverName string, config LeaderElectionConfig) Runner {
+	if !config.Enabled || mode != ModeController {
+		return ctrl
+	}
+	return &leaderElectionRunner{
+		ctrl:       ctrl,
+		kubeClient: config.KubeClient,
+		leaseName:  LeaseName(driverName),
+		namespace:  config.Namespace,
+	}
+}
+
+// leaderElectionRunner runs the controller while it holds the Lease. The process exits when the Lease is lost.
+type leaderElectionRunner struct {
+	ctrl       Runner
+	kubeClient kubernetes.Interface
+	leaseName  string
+	namespace  string
+}
+
+// Run returns once stopCh is closed. The ProvisionerCapability is kept on exit, so it does not wait for the
+// controller to stop.
+func (r *leaderElectionRunner) Run(stopCh <-chan struct{}) {
+	le := leaderelection.NewLeaderElection(r.kubeClient, r.leaseName, func(ctx context.Context) {
+		r.ctrl.Run(stopCh)
+	})
+	if r.namespace != "" {
+		le.WithNamespace(r.namespace)
+	}
+	go func() {
+		if err := le.Run(); err != nil {
+			klog.Fatalf("Error initializing leader election: %s", err)
+		}
+	}()
+	<-stopCh
+}
+
+// WaitForDriverName calls getDriverName with exponential backoff up to maxDelay until it succeeds or stopCh is
+// closed. A slow starting CSI driver delays the sidecar instead of crashing it.
+func WaitForDriverName(getDriverName func() (string, error), initialDelay, maxDelay time.Duration, stopCh <-chan struct{}) (string, error) {
+	delay := initialDelay
+	for {
+		name, err := getDriverName()
+		if err == nil {
+			return name, nil
+		}
+		klog.Errorf("Error getting CSI driver name, retry in %s: %s", delay, err)
+		select {
+		case <-stopCh:
+			return "", err
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
@@ -0,0 +1,223 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+// Package snapshot reads VolumeSnapshotClasses of snapshot.storage.k8s.io regardless of the served API version.
+// Objects are watched with the dynamic client and converted into VolumeSnapshotClass of this package.
+package snapshot
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	GroupName = "snapshot.storage.k8s.io"
+	V1        = "v1"
+	V1beta1   = "v1beta1"
+)
+
+// SupportedVersions lists the supported versions of snapshot.storage.k8s.io in order of preference.
+var SupportedVersions = []string{V1, V1beta1}
+
+// VolumeSnapshotClass is the version independent representation of a VolumeSnapshotClass.
+// The fields used here have the same layout in v1 and v1beta1.
+type VolumeSnapshotClass struct {
+	metav1.ObjectMeta
+	Driver         string
+	DeletionPolicy string
+	Parameters     map[string]string
+}
+
+// ClassResource returns the resource of VolumeSnapshotClass in the given version.
+func ClassResource(version string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: GroupName, Version: version, Resource: "volumesnapshotclasses"}
+}
+
+// DiscoverVersion returns the most preferred version of snapshot.storage.k8s.io served by the API server.
+// An empty version is returned if none of the supported versions is served.
+func DiscoverVersion(client discovery.ServerGroupsInterface) (string, error) {
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return "", err
+	}
+	served := map[string]bool{}
+	for _, group := range groups.Groups {
+		if group.Name != GroupName {
+			continue
+		}
+		for _, version := range group.Versions {
+			served[version.Version] = true
+		}
+	}
+	for _, version := range SupportedVersions {
+		if served[version] {
+			return version, nil
+		}
+	}
+	return "", nil
+}
+
+// WaitForVersion polls discovery until one of the supported versions of snapshot.storage.k8s.io is served,
+// e.g. after the snapshot CRDs are installed. Discovery errors are logged and retried.
+func WaitForVersion(client discovery.ServerGroupsInterface, interval time.Duration, stopCh <-chan struct{}) (string, error) {
+	var version string
+	err := wait.PollImmediateUntil(interval, func() (bool, error) {
+		var err error
+		version, err = DiscoverVersion(client)
+		if err != nil {
+			klog.Errorf("Discover %s error: %s", GroupName, err)
+			return false, nil
+		}
+		return version != "", nil
+	}, stopCh)
+	return version, err
+}
+
+// ClassFromObject converts an object received from the informer into VolumeSnapshotClass.
+// Tombstones of deleted objects are unwrapped.
+func ClassFromObject(obj interface{}) (*VolumeSnapshotClass, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("error decoding object, invalid type %T", obj)
+	}
+	return ClassFromUnstructured(u)
+}
+
+// ClassFromUnstructured converts a VolumeSnapshotClass of any supported version into VolumeSnapshotClass.
+func ClassFromUnstructured(u *unstructured.Unstructured) (*VolumeSnapshotClass, error) {
+	gv, err := schema.ParseGroupVersion(u.GetAPIVersion())
+	if err != nil {
+		return nil, err
+	}
+	if gv.Group != GroupName || !isSupportedVersion(gv.Version) {
+		return nil, fmt.Errorf("unsupported api version %s of VolumeSnapshotClass %s", u.GetAPIVersion(), u.GetName())
+	}
+	class := &VolumeSnapshotClass{}
+	metadata, _, err := unstructured.NestedMap(u.Object, "metadata")
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(metadata, &class.ObjectMeta); err != nil {
+		return nil, err
+	}
+	if class.Driver, _, err = unstructured.NestedString(u.Object, "driver"); err != nil {
+		return nil, err
+	}
+	if class.DeletionPolicy, _, err = unstructured.NestedString(u.Object, "deletionPolicy"); err != nil {
+		return nil, err
+	}
+	if class.Parameters, _, err = unstructured.NestedStringMap(u.Object, "parameters"); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// ToUnstructured converts VolumeSnapshotClass into an object of the given version.
+func (in *VolumeSnapshotClass) ToUnstructured(version string) (*unstructured.Unstructured, error) {
+	metadata, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&in.ObjectMeta)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": metadata,
+		"driver":   in.Driver,
+	}}
+	u.SetAPIVersion(schema.GroupVersion{Group: GroupName, Version: version}.String())
+	u.SetKind("VolumeSnapshotClass")
+	if in.DeletionPolicy != "" {
+		u.Object["deletionPolicy"] = in.DeletionPolicy
+	}
+	if in.Parameters != nil {
+		if err := unstructured.SetNestedStringMap(u.Object, in.Parameters, "parameters"); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+func isSupportedVersion(version string) bool {
+	for _, v := range SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassLister lists VolumeSnapshotClasses from the informer cache.
+type ClassLister interface {
+	List(selector labels.Selector) ([]*VolumeSnapshotClass, error)
+}
+
+// ClassInformer provides access to a shared informer and lister of VolumeSnapshotClasses.
+type ClassInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() ClassLister
+}
+
+type classInformer struct {
+	informer informers.GenericInformer
+}
+
+// NewClassInformer returns the VolumeSnapshotClass informer of the given version from the dynamic informer factory.
+func NewClassInformer(factory dynamicinformer.DynamicSharedInformerFactory, version string) ClassInformer {
+	return &classInformer{informer: factory.ForResource(ClassResource(version))}
+}
+
+func (i *classInformer) Informer() cache.SharedIndexInformer {
+	return i.informer.Informer()
+}
+
+func (i *classInformer) Lister() ClassLister {
+	return &classLister{indexer: i.informer.Informer().GetIndexer()}
+}
+
+type classLister struct {
+	indexer cache.Indexer
+}
+
+func (l *classLister) List(selector labels.Selector) ([]*VolumeSnapshotClass, error) {
+	var ret []*VolumeSnapshotClass
+	var errs []error
+	if err := cache.ListAll(l.indexer, selector, func(obj interface{}) {
+		class, err := ClassFromObject(obj)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		ret = append(ret, class)
+	}); err != nil {
+		return nil, err
+	}
+	return ret, utilerrors.NewAggregate(errs)
+}
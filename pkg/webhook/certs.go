@@ -0,0 +1,422 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	pkgerrors "github.com/pkg/errors"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCertValidity is the validity of the generated CA and serving certificate.
+	DefaultCertValidity = 90 * 24 * time.Hour
+	// certSyncPeriod is the period to check the certificate and the caBundles.
+	certSyncPeriod = 10 * time.Minute
+	// caCertKey is the key of the CA bundle in the Secret, the other keys are the ones of kubernetes.io/tls.
+	caCertKey = "ca.crt"
+)
+
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// CertManager keeps a self-signed serving certificate of the webhook Service in a Secret. The certificate is
+// renewed once less than a third of its validity is left, served without restart, and its CA is patched into the
+// webhook configurations and the conversion webhooks of CRDs calling the Service.
+// Replicas of the webhook share the Secret, concurrent renewals are resolved by the resourceVersion.
+type CertManager struct {
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
+
+	namespace   string
+	secretName  string
+	serviceName string
+	validity    time.Duration
+
+	mutex sync.RWMutex
+	cert  *tls.Certificate
+}
+
+// NewCertManager returns a CertManager of the Service in the namespace, keeping the certificate in the Secret of
+// the same namespace.
+func NewCertManager(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, namespace string,
+	secretName string, serviceName string, validity time.Duration) *CertManager {
+	return &CertManager{
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		namespace:     namespace,
+		secretName:    secretName,
+		serviceName:   serviceName,
+		validity:      validity,
+	}
+}
+
+// GetCertificate returns the current serving certificate, it is used as tls.Config.GetCertificate.
+func (m *CertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.cert == nil {
+		return nil, fmt.Errorf("serving certificate of Service %s/%s is not ready", m.namespace, m.serviceName)
+	}
+	return m.cert, nil
+}
+
+// Run loads or generates the certificate, then checks it periodically until stopCh is closed.
+// It returns an error if no certificate can be served.
+func (m *CertManager) Run(stopCh <-chan struct{}) error {
+	if err := m.sync(); err != nil {
+		return err
+	}
+	go wait.Until(func() {
+		if err := m.sync(); err != nil {
+			utilruntime.HandleError(err)
+		}
+	}, certSyncPeriod, stopCh)
+	return nil
+}
+
+// sync renews the certificate in the Secret if needed, serves it and patches its CA into the webhooks.
+func (m *CertManager) sync() error {
+	var secret *corev1.Secret
+	// Another replica may renew the certificate at the same time, the loser reads the Secret again.
+	err := retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return k8serrors.IsConflict(err) || k8serrors.IsAlreadyExists(err)
+	}, func() error {
+		var err error
+		secret, err = m.applySecret()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return pkgerrors.Wrapf(err, "invalid certificate in Secret %s/%s", m.namespace, m.secretName)
+	}
+	m.mutex.Lock()
+	m.cert = &cert
+	m.mutex.Unlock()
+	// Failing to patch a webhook must not stop serving the others.
+	if err := m.patchCABundle(secret.Data[caCertKey]); err != nil {
+		utilruntime.HandleError(err)
+	}
+	return nil
+}
+
+// applySecret returns the Secret with a valid certificate, generating a new one if missing or about to expire.
+func (m *CertManager) applySecret() (*corev1.Secret, error) {
+	secrets := m.kubeClient.CoreV1().Secrets(m.namespace)
+	secret, err := secrets.Get(m.secretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.Infof("Generate certificate in Secret %s/%s", m.namespace, m.secretName)
+		data, err := m.generateCertificate(nil)
+		if err != nil {
+			return nil, err
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: m.secretName, Namespace: m.namespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       data,
+		}
+		if secret, err = secrets.Create(secret); err != nil {
+			return nil, pkgerrors.Wrapf(err, "create Secret %s/%s error", m.namespace, m.secretName)
+		}
+		return secret, nil
+	}
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "get Secret %s/%s error", m.namespace, m.secretName)
+	}
+	reason := m.checkCertificate(secret.Data)
+	if reason == "" {
+		return secret, nil
+	}
+	klog.Infof("Renew certificate in Secret %s/%s: %s", m.namespace, m.secretName, reason)
+	data, err := m.generateCertificate(secret.Data[caCertKey])
+	if err != nil {
+		return nil, err
+	}
+	secret = secret.DeepCopy()
+	secret.Data = data
+	if secret, err = secrets.Update(secret); err != nil {
+		return nil, pkgerrors.Wrapf(err, "update Secret %s/%s error", m.namespace, m.secretName)
+	}
+	return secret, nil
+}
+
+// dnsNames returns the names the webhook Service is called by.
+func (m *CertManager) dnsNames() []string {
+	return []string{
+		m.serviceName,
+		m.serviceName + "." + m.namespace,
+		m.serviceName + "." + m.namespace + ".svc",
+		m.serviceName + "." + m.namespace + ".svc.cluster.local",
+	}
+}
+
+// checkCertificate returns why the certificate in the Secret data must be renewed, or "" if it is valid.
+func (m *CertManager) checkCertificate(data map[string][]byte) string {
+	if _, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey]); err != nil {
+		return fmt.Sprintf("invalid key pair: %s", err)
+	}
+	block, _ := pem.Decode(data[corev1.TLSCertKey])
+	if block == nil {
+		return "no certificate"
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Sprintf("invalid certificate: %s", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data[caCertKey]) {
+		return "no CA certificate"
+	}
+	now := time.Now()
+	for _, name := range m.dnsNames() {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: name, Roots: roots, CurrentTime: now}); err != nil {
+			return fmt.Sprintf("not valid for %s: %s", name, err)
+		}
+	}
+	if left := cert.NotAfter.Sub(now); left < m.validity/3 {
+		return fmt.Sprintf("expires at %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return ""
+}
+
+// generateCertificate returns the Secret data of a new CA and serving certificate. The CA is generated with each
+// certificate and its key is dropped. The bundle keeps the previous CA while it is valid, so the API server trusts
+// replicas still serving the previous certificate until they reload it.
+func (m *CertManager) generateCertificate(previousCA []byte) (map[string][]byte, error) {
+	now := time.Now()
+	// Tolerate clock skew between the webhook and the API server.
+	notBefore := now.Add(-time.Hour)
+	notAfter := now.Add(m.validity)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "generate CA key error")
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%s-ca@%d", m.serviceName, now.Unix())},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "create CA certificate error")
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "parse CA certificate error")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "generate serving key error")
+	}
+	template := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: m.serviceName + "." + m.namespace + ".svc"},
+		DNSNames:     m.dnsNames(),
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "create serving certificate error")
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "marshal serving key error")
+	}
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	if block, _ := pem.Decode(previousCA); block != nil {
+		if previous, err := x509.ParseCertificate(block.Bytes); err == nil && now.Before(previous.NotAfter) {
+			caBundle = append(caBundle, pem.EncodeToMemory(block)...)
+		}
+	}
+	return map[string][]byte{
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		caCertKey:               caBundle,
+	}, nil
+}
+
+func newSerialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		// The system random source is broken, nothing works anyway.
+		panic(err)
+	}
+	return serial
+}
+
+// calledService tells whether the webhook client config calls the Service of the manager.
+func (m *CertManager) calledService(service *admissionregistrationv1beta1.ServiceReference) bool {
+	return service != nil && service.Namespace == m.namespace && service.Name == m.serviceName
+}
+
+// patchCABundle sets the caBundle of the mutating, validating and conversion webhooks calling the Service.
+// Others may update the webhook configurations meanwhile, they are read again on conflict.
+func (m *CertManager) patchCABundle(caBundle []byte) error {
+	var errs []error
+	mutatingClient := m.kubeClient.AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
+	mutatingConfigs, err := mutatingClient.List(metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, pkgerrors.Wrap(err, "list MutatingWebhookConfigurations error"))
+	} else {
+		for i := range mutatingConfigs.Items {
+			config := &mutatingConfigs.Items[i]
+			if !m.setMutatingCABundle(config, caBundle) {
+				continue
+			}
+			err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				_, err := mutatingClient.Update(config)
+				if k8serrors.IsConflict(err) {
+					if latest, err := mutatingClient.Get(config.GetName(), metav1.GetOptions{}); err == nil {
+						m.setMutatingCABundle(latest, caBundle)
+						config = latest
+					}
+				}
+				return err
+			})
+			if err != nil {
+				errs = append(errs, pkgerrors.Wrapf(err, "update MutatingWebhookConfiguration %s error", config.GetName()))
+				continue
+			}
+			klog.Infof("caBundle of MutatingWebhookConfiguration %s updated", config.GetName())
+		}
+	}
+
+	validatingClient := m.kubeClient.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations()
+	validatingConfigs, err := validatingClient.List(metav1.ListOptions{})
+	if err != nil {
+		errs = append(errs, pkgerrors.Wrap(err, "list ValidatingWebhookConfigurations error"))
+	} else {
+		for i := range validatingConfigs.Items {
+			config := &validatingConfigs.Items[i]
+			if !m.setValidatingCABundle(config, caBundle) {
+				continue
+			}
+			err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				_, err := validatingClient.Update(config)
+				if k8serrors.IsConflict(err) {
+					if latest, err := validatingClient.Get(config.GetName(), metav1.GetOptions{}); err == nil {
+						m.setValidatingCABundle(latest, caBundle)
+						config = latest
+					}
+				}
+				return err
+			})
+			if err != nil {
+				errs = append(errs, pkgerrors.Wrapf(err, "update ValidatingWebhookConfiguration %s error", config.GetName()))
+				continue
+			}
+			klog.Infof("caBundle of ValidatingWebhookConfiguration %s updated", config.GetName())
+		}
+	}
+
+	errs = append(errs, m.patchConversionCABundle(caBundle)...)
+	return utilerrors.NewAggregate(errs)
+}
+
+// setMutatingCABundle sets the caBundle of the webhooks calling the Service, it returns whether any changed.
+func (m *CertManager) setMutatingCABundle(config *admissionregistrationv1beta1.MutatingWebhookConfiguration, caBundle []byte) bool {
+	changed := false
+	for j := range config.Webhooks {
+		clientConfig := &config.Webhooks[j].ClientConfig
+		if m.calledService(clientConfig.Service) && !bytes.Equal(clientConfig.CABundle, caBundle) {
+			clientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	return changed
+}
+
+// setValidatingCABundle sets the caBundle of the webhooks calling the Service, it returns whether any changed.
+func (m *CertManager) setValidatingCABundle(config *admissionregistrationv1beta1.ValidatingWebhookConfiguration, caBundle []byte) bool {
+	changed := false
+	for j := range config.Webhooks {
+		clientConfig := &config.Webhooks[j].ClientConfig
+		if m.calledService(clientConfig.Service) && !bytes.Equal(clientConfig.CABundle, caBundle) {
+			clientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	return changed
+}
+
+// patchConversionCABundle sets the caBundle of the conversion webhooks of CRDs calling the Service.
+func (m *CertManager) patchConversionCABundle(caBundle []byte) []error {
+	crds, err := m.dynamicClient.Resource(crdResource).List(metav1.ListOptions{})
+	if err != nil {
+		return []error{pkgerrors.Wrap(err, "list CustomResourceDefinitions error")}
+	}
+	encoded := base64.StdEncoding.EncodeToString(caBundle)
+	var errs []error
+	for _, crd := range crds.Items {
+		clientConfig, found, err := unstructured.NestedMap(crd.Object, "spec", "conversion", "webhook", "clientConfig")
+		if err != nil || !found {
+			continue
+		}
+		namespace, _, _ := unstructured.NestedString(clientConfig, "service", "namespace")
+		name, _, _ := unstructured.NestedString(clientConfig, "service", "name")
+		current, _, _ := unstructured.NestedString(clientConfig, "caBundle")
+		if namespace != m.namespace || name != m.serviceName || current == encoded {
+			continue
+		}
+		patch := []byte(fmt.Sprintf(`{"spec":{"conversion":{"webhook":{"clientConfig":{"caBundle":%q}}}}}`, encoded))
+		if _, err := m.dynamicClient.Resource(crdResource).Patch(crd.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			errs = append(errs, pkgerrors.Wrapf(err, "patch CustomResourceDefinition %s error", crd.GetName()))
+			continue
+		}
+		klog.Infof("caBundle of CustomResourceDefinition %s updated", crd.GetName())
+	}
+	return errs
+}
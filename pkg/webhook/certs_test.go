@@ -0,0 +1,179 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertManagerCheckCertificate(t *testing.T) {
+	manager := NewCertManager(nil, nil, "kube-system", "storage-capability-webhook-certs", "storage-capability", DefaultCertValidity)
+	valid, err := manager.generateCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortLived, err := NewCertManager(nil, nil, "kube-system", "storage-capability-webhook-certs", "storage-capability", 24*time.Hour).generateCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherService, err := NewCertManager(nil, nil, "kube-system", "storage-capability-webhook-certs", "other", DefaultCertValidity).generateCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		data         map[string][]byte
+		expectReason string
+	}{
+		{
+			name: "generated certificate",
+			data: valid,
+		},
+		{
+			name:         "expires within a third of validity",
+			data:         shortLived,
+			expectReason: "expires at",
+		},
+		{
+			name:         "DNS name mismatch",
+			data:         otherService,
+			expectReason: "not valid for storage-capability",
+		},
+		{
+			name:         "CA of another certificate",
+			data:         map[string][]byte{corev1.TLSCertKey: valid[corev1.TLSCertKey], corev1.TLSPrivateKeyKey: valid[corev1.TLSPrivateKeyKey], caCertKey: otherService[caCertKey]},
+			expectReason: "not valid for",
+		},
+		{
+			name:         "empty Secret",
+			data:         map[string][]byte{},
+			expectReason: "invalid key pair",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason := manager.checkCertificate(test.data)
+			if test.expectReason == "" && reason != "" || !strings.HasPrefix(reason, test.expectReason) {
+				t.Errorf("expected reason %q, got %q", test.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCertManagerKeepPreviousCA(t *testing.T) {
+	manager := NewCertManager(nil, nil, "kube-system", "storage-capability-webhook-certs", "storage-capability", DefaultCertValidity)
+	previous, err := manager.generateCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := manager.generateCertificate(previous[caCertKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason := manager.checkCertificate(renewed); reason != "" {
+		t.Errorf("expected renewed certificate to be valid, got %q", reason)
+	}
+	if !bytes.HasSuffix(renewed[caCertKey], previous[caCertKey]) || bytes.Equal(renewed[caCertKey], previous[caCertKey]) {
+		t.Errorf("expected the bundle to hold the new and the previous CA, got\n%s", renewed[caCertKey])
+	}
+	// Replicas still serving the previous certificate are trusted during the rotation.
+	stale := map[string][]byte{
+		corev1.TLSCertKey:       previous[corev1.TLSCertKey],
+		corev1.TLSPrivateKeyKey: previous[corev1.TLSPrivateKeyKey],
+		caCertKey:               renewed[caCertKey],
+	}
+	if reason := manager.checkCertificate(stale); reason != "" {
+		t.Errorf("expected previous certificate to be trusted by the bundle, got %q", reason)
+	}
+	// Only the previous CA is kept, not the whole history.
+	again, err := manager.generateCertificate(renewed[caCertKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certs := bytes.Count(again[caCertKey], []byte("BEGIN CERTIFICATE")); certs != 2 {
+		t.Errorf("expected 2 certificates in the bundle, got %d", certs)
+	}
+}
+
+func TestCertManagerPatchCABundle(t *testing.T) {
+	service := &admissionregistrationv1beta1.ServiceReference{Namespace: "kube-system", Name: "storage-capability"}
+	other := &admissionregistrationv1beta1.ServiceReference{Namespace: "kube-system", Name: "other"}
+	client := k8sfake.NewSimpleClientset(
+		&admissionregistrationv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "storage-capability"},
+			Webhooks: []admissionregistrationv1beta1.MutatingWebhook{
+				{Name: "inject", ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{Service: service}},
+				{Name: "other", ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{Service: other, CABundle: []byte("other")}},
+			},
+		},
+		&admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "storage-capability"},
+			Webhooks: []admissionregistrationv1beta1.ValidatingWebhook{
+				{Name: "validate", ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{Service: service}},
+			},
+		},
+	)
+	// Another writer updates each configuration once between the list and the update.
+	conflicts := map[string]bool{}
+	client.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		resource := action.GetResource().Resource
+		if conflicts[resource] {
+			return false, nil, nil
+		}
+		conflicts[resource] = true
+		return true, nil, k8serrors.NewConflict(action.GetResource().GroupResource(), "storage-capability", errors.New("modified"))
+	})
+	manager := NewCertManager(client, dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), "kube-system",
+		"storage-capability-webhook-certs", "storage-capability", DefaultCertValidity)
+	if err := manager.patchCABundle([]byte("ca")); err != nil {
+		t.Fatalf("patch caBundle error: %v", err)
+	}
+	mutating, err := client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Get("storage-capability", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(mutating.Webhooks[0].ClientConfig.CABundle); got != "ca" {
+		t.Errorf("expected caBundle of mutating webhook to be updated, got %q", got)
+	}
+	if got := string(mutating.Webhooks[1].ClientConfig.CABundle); got != "other" {
+		t.Errorf("expected caBundle of other service to be kept, got %q", got)
+	}
+	validating, err := client.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("storage-capability", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(validating.Webhooks[0].ClientConfig.CABundle); got != "ca" {
+		t.Errorf("expected caBundle of validating webhook to be updated, got %q", got)
+	}
+	if len(conflicts) != 2 {
+		t.Errorf("expected a conflict on both configurations, got %v", conflicts)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"fmt"
+	pkgerrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// annotationSidecarConfig overrides the SidecarInjectionConfig of a single pod. The value has the same
+	// layout as the ConfigMap data, fields which are not set are taken from the ConfigMap.
+	annotationSidecarConfig = "storage.kubesphere.io/storage-capability-sidecar-config"
+	// SidecarConfigKey is the key of SidecarInjectionConfig in the ConfigMap data.
+	SidecarConfigKey = "config.yaml"
+)
+
+// SidecarInjectionConfig configures the sidecar container injected into CSI controller pods.
+type SidecarInjectionConfig struct {
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// Args are appended to the arguments set by the webhook, e.g. --v=5.
+	Args []string `json:"args,omitempty"`
+	// Resources and SecurityContext of the sidecar container.
+	Resources       corev1.ResourceRequirements `json:"resources,omitempty"`
+	SecurityContext *corev1.SecurityContext     `json:"securityContext,omitempty"`
+	// Env is added to the environment of the sidecar. A variable set by the webhook is replaced by the one of the same name.
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// ResyncPeriod and Timeout are passed to --resync-period and --timeout of the sidecar if set.
+	ResyncPeriod *metav1.Duration `json:"resyncPeriod,omitempty"`
+	Timeout      *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// DefaultSidecarInjectionConfig is used if no ConfigMap is found.
+func DefaultSidecarInjectionConfig() *SidecarInjectionConfig {
+	return &SidecarInjectionConfig{
+		Image:           storageCapabilityImage,
+		ImagePullPolicy: corev1.PullAlways,
+		Args:            []string{"--v=5"},
+	}
+}
+
+// ParseSidecarInjectionConfig decodes SidecarInjectionConfig from YAML or JSON.
+func ParseSidecarInjectionConfig(data string) (*SidecarInjectionConfig, error) {
+	config := &SidecarInjectionConfig{}
+	if strings.TrimSpace(data) == "" {
+		return config, nil
+	}
+	if err := yaml.NewYAMLOrJSONDecoder(strings.NewReader(data), len(data)).Decode(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// merge returns a copy of the config with the fields set in the override replaced.
+func (c *SidecarInjectionConfig) merge(override *SidecarInjectionConfig) *SidecarInjectionConfig {
+	res := c.DeepCopy()
+	if override == nil {
+		return res
+	}
+	if override.Image != "" {
+		res.Image = override.Image
+	}
+	if override.ImagePullPolicy != "" {
+		res.ImagePullPolicy = override.ImagePullPolicy
+	}
+	if override.Args != nil {
+		res.Args = append([]string(nil), override.Args...)
+	}
+	if override.Resources.Limits != nil {
+		res.Resources.Limits = override.Resources.Limits.DeepCopy()
+	}
+	if override.Resources.Requests != nil {
+		res.Resources.Requests = override.Resources.Requests.DeepCopy()
+	}
+	if override.SecurityContext != nil {
+		res.SecurityContext = override.SecurityContext.DeepCopy()
+	}
+	res.Env = mergeEnv(res.Env, override.Env)
+	if override.ResyncPeriod != nil {
+		res.ResyncPeriod = override.ResyncPeriod.DeepCopy()
+	}
+	if override.Timeout != nil {
+		res.Timeout = override.Timeout.DeepCopy()
+	}
+	return res
+}
+
+// mergeEnv replaces the variables of the same name and appends the others.
+func mergeEnv(env []corev1.EnvVar, override []corev1.EnvVar) []corev1.EnvVar {
+	res := append([]corev1.EnvVar(nil), env...)
+	for _, o := range override {
+		replaced := false
+		for i := range res {
+			if res[i].Name == o.Name {
+				res[i] = *o.DeepCopy()
+				replaced = true
+			}
+		}
+		if !replaced {
+			res = append(res, *o.DeepCopy())
+		}
+	}
+	return res
+}
+
+// DeepCopy returns a deep copy of the config.
+func (c *SidecarInjectionConfig) DeepCopy() *SidecarInjectionConfig {
+	res := &SidecarInjectionConfig{
+		Image:           c.Image,
+		ImagePullPolicy: c.ImagePullPolicy,
+		Args:            append([]string(nil), c.Args...),
+		Resources:       *c.Resources.DeepCopy(),
+		SecurityContext: c.SecurityContext.DeepCopy(),
+		Env:             mergeEnv(nil, c.Env),
+	}
+	if c.ResyncPeriod != nil {
+		res.ResyncPeriod = c.ResyncPeriod.DeepCopy()
+	}
+	if c.Timeout != nil {
+		res.Timeout = c.Timeout.DeepCopy()
+	}
+	return res
+}
+
+// podSidecarConfig returns the config for the pod, overridden by the annotation of the pod.
+func podSidecarConfig(config *SidecarInjectionConfig, annotations map[string]string) (*SidecarInjectionConfig, error) {
+	data, ok := annotations[annotationSidecarConfig]
+	if !ok {
+		return config, nil
+	}
+	override, err := ParseSidecarInjectionConfig(data)
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "invalid annotation %s", annotationSidecarConfig)
+	}
+	return config.merge(override), nil
+}
+
+// SidecarInjectionConfigStore holds the SidecarInjectionConfig loaded from a ConfigMap.
+type SidecarInjectionConfigStore struct {
+	mutex  sync.RWMutex
+	config *SidecarInjectionConfig
+}
+
+// NewSidecarInjectionConfigStore returns a store holding the default config.
+func NewSidecarInjectionConfigStore() *SidecarInjectionConfigStore {
+	return &SidecarInjectionConfigStore{config: DefaultSidecarInjectionConfig()}
+}
+
+// Get returns the current config.
+func (s *SidecarInjectionConfigStore) Get() *SidecarInjectionConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.config
+}
+
+func (s *SidecarInjectionConfigStore) set(config *SidecarInjectionConfig) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.config = config
+}
+
+// load merges the ConfigMap into the default config. An invalid ConfigMap is logged and the current config is kept.
+func (s *SidecarInjectionConfigStore) load(cm *corev1.ConfigMap) {
+	config, err := ParseSidecarInjectionConfig(cm.Data[SidecarConfigKey])
+	if err != nil {
+		klog.Errorf("Invalid sidecar injection config %s/%s, keep the current config: %s", cm.GetNamespace(), cm.GetName(), err)
+		return
+	}
+	klog.Infof("Load sidecar injection config %s/%s", cm.GetNamespace(), cm.GetName())
+	s.set(DefaultSidecarInjectionConfig().merge(config))
+}
+
+// Watch hot-reloads the config from the ConfigMap of the given namespace and name until stopCh is closed.
+// The default config is used while the ConfigMap does not exist.
+func (s *SidecarInjectionConfigStore) Watch(client kubernetes.Interface, namespace string, name string, stopCh <-chan struct{}) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 10*time.Minute,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.load(obj.(*corev1.ConfigMap))
+		},
+		UpdateFunc: func(old, new interface{}) {
+			s.load(new.(*corev1.ConfigMap))
+		},
+		DeleteFunc: func(obj interface{}) {
+			klog.Infof("Sidecar injection config %s/%s deleted, use the default config", namespace, name)
+			s.set(DefaultSidecarInjectionConfig())
+		},
+	})
+	factory.Start(stopCh)
+	if ok := cache.WaitForCacheSync(stopCh, informer.HasSynced); !ok {
+		return fmt.Errorf("failed to wait for ConfigMap %s/%s to sync", namespace, name)
+	}
+	return nil
+}
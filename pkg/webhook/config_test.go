@@ -0,0 +1,212 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSidecarInjectionConfigMerge(t *testing.T) {
+	base := &SidecarInjectionConfig{
+		Image:           "sidecar:v1",
+		ImagePullPolicy: corev1.PullAlways,
+		Args:            []string{"--v=5"},
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("80m")},
+		},
+		Env:     []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+		Timeout: &metav1.Duration{Duration: time.Minute},
+	}
+	tests := []struct {
+		name     string
+		override *SidecarInjectionConfig
+		expected *SidecarInjectionConfig
+	}{
+		{
+			name:     "nil override",
+			expected: base,
+		},
+		{
+			name:     "empty override",
+			override: &SidecarInjectionConfig{},
+			expected: base,
+		},
+		{
+			name: "override takes precedence",
+			override: &SidecarInjectionConfig{
+				Image:           "sidecar:v2",
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Args:            []string{},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+				},
+				SecurityContext: &corev1.SecurityContext{},
+				Env:             []corev1.EnvVar{{Name: "B", Value: "3"}, {Name: "C", Value: "4"}},
+				ResyncPeriod:    &metav1.Duration{Duration: time.Second},
+				Timeout:         &metav1.Duration{Duration: time.Hour},
+			},
+			expected: &SidecarInjectionConfig{
+				Image:           "sidecar:v2",
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				// An empty list clears the args.
+				Args: nil,
+				Resources: corev1.ResourceRequirements{
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("80m")},
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")},
+				},
+				SecurityContext: &corev1.SecurityContext{},
+				Env:             []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "3"}, {Name: "C", Value: "4"}},
+				ResyncPeriod:    &metav1.Duration{Duration: time.Second},
+				Timeout:         &metav1.Duration{Duration: time.Hour},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := base.merge(test.override)
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, res)
+			}
+			if res == base {
+				t.Error("expected a copy of the config")
+			}
+		})
+	}
+}
+
+func TestSidecarInjectionConfigDeepCopy(t *testing.T) {
+	config := &SidecarInjectionConfig{
+		Image:           "sidecar:v1",
+		Args:            []string{"--v=5"},
+		Resources:       corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("80m")}},
+		SecurityContext: &corev1.SecurityContext{RunAsNonRoot: new(bool)},
+		Env:             []corev1.EnvVar{{Name: "A", Value: "1"}},
+		ResyncPeriod:    &metav1.Duration{Duration: time.Minute},
+		Timeout:         &metav1.Duration{Duration: time.Minute},
+	}
+	res := config.DeepCopy()
+	if !reflect.DeepEqual(res, config) {
+		t.Fatalf("expected %+v, got %+v", config, res)
+	}
+	res.Args[0] = "--v=1"
+	res.Resources.Limits[corev1.ResourceCPU] = resource.MustParse("1")
+	*res.SecurityContext.RunAsNonRoot = true
+	res.Env[0].Value = "2"
+	res.ResyncPeriod.Duration = time.Hour
+	res.Timeout.Duration = time.Hour
+	expected := &SidecarInjectionConfig{
+		Image:           "sidecar:v1",
+		Args:            []string{"--v=5"},
+		Resources:       corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("80m")}},
+		SecurityContext: &corev1.SecurityContext{RunAsNonRoot: new(bool)},
+		Env:             []corev1.EnvVar{{Name: "A", Value: "1"}},
+		ResyncPeriod:    &metav1.Duration{Duration: time.Minute},
+		Timeout:         &metav1.Duration{Duration: time.Minute},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("changing the copy changed the config: %+v", config)
+	}
+}
+
+func TestPodSidecarConfig(t *testing.T) {
+	config := DefaultSidecarInjectionConfig()
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    *SidecarInjectionConfig
+		expectErr   bool
+	}{
+		{
+			name:     "no annotation",
+			expected: config,
+		},
+		{
+			name:        "yaml override",
+			annotations: map[string]string{annotationSidecarConfig: "image: sidecar:v2\nargs: [\"--v=1\"]"},
+			expected: &SidecarInjectionConfig{
+				Image:           "sidecar:v2",
+				ImagePullPolicy: config.ImagePullPolicy,
+				Args:            []string{"--v=1"},
+			},
+		},
+		{
+			name:        "json override",
+			annotations: map[string]string{annotationSidecarConfig: `{"timeout": "30s"}`},
+			expected: &SidecarInjectionConfig{
+				Image:           config.Image,
+				ImagePullPolicy: config.ImagePullPolicy,
+				Args:            config.Args,
+				Timeout:         &metav1.Duration{Duration: 30 * time.Second},
+			},
+		},
+		{
+			name:        "invalid json",
+			annotations: map[string]string{annotationSidecarConfig: `{"image": `},
+			expectErr:   true,
+		},
+		{
+			name:        "invalid field type",
+			annotations: map[string]string{annotationSidecarConfig: `{"args": "--v=1"}`},
+			expectErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := podSidecarConfig(config, test.annotations)
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(res, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestSidecarInjectionConfigStoreLoad(t *testing.T) {
+	store := NewSidecarInjectionConfigStore()
+	newConfigMap := func(data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "sidecar-config", Namespace: "kube-system"},
+			Data:       map[string]string{SidecarConfigKey: data},
+		}
+	}
+	store.load(newConfigMap("image: sidecar:v2"))
+	expected := DefaultSidecarInjectionConfig()
+	expected.Image = "sidecar:v2"
+	if !reflect.DeepEqual(store.Get(), expected) {
+		t.Errorf("expected the ConfigMap merged into the default config %+v, got %+v", expected, store.Get())
+	}
+	// An invalid ConfigMap keeps the current config.
+	store.load(newConfigMap("image: [sidecar"))
+	if !reflect.DeepEqual(store.Get(), expected) {
+		t.Errorf("expected the current config %+v to be kept, got %+v", expected, store.Get())
+	}
+}
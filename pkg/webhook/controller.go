@@ -24,26 +24,34 @@ import (
 	"errors"
 	"fmt"
 	pkgerrors "github.com/pkg/errors"
+	"hash/fnv"
 	"io/ioutil"
 	"k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kuberuntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/rand"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog"
 	"net/http"
+	"strings"
 	"text/template"
+	"time"
 )
 
 const (
 	annotationAddress      = "storage.kubesphere.io/storage-capability-address"
 	annotationVolumeName   = "storage.kubesphere.io/storage-capability-volume-name"
 	annotationMountPath    = "storage.kubesphere.io/storage-capability-mount-path"
+	sidecarContainerName   = "storage-capability"
 	storageCapabilityImage = "kubespheredev/storage-capability-sidecar:v0.1.0"
 	jsonContentType        = `application/json`
 )
@@ -62,8 +70,11 @@ type patchOperation struct {
 
 type admitFunc func(*v1.AdmissionRequest, kubernetes.Interface) ([]patchOperation, error)
 
+// reviewFunc returns the patches and warnings of an allowed request, or an error to deny it.
+type reviewFunc func(*v1.AdmissionRequest, kubernetes.Interface) ([]patchOperation, []string, error)
+
 // doServeAdmitFunc parese the HTTP request for an admission controller webhook
-func doServeAdmitFunc(w http.ResponseWriter, r *http.Request, admit admitFunc, k8sClient kubernetes.Interface) ([]byte, error) {
+func doServeAdmitFunc(w http.ResponseWriter, r *http.Request, review reviewFunc, k8sClient kubernetes.Interface) ([]byte, error) {
 	// Step 1: Request validation. Only handle POST request with a body and json content type.
 	klog.V(4).Infof("Step 1: Request validation. Only handle POST request with a body and json content type.")
 	if r.Method != http.MethodPost {
@@ -85,45 +96,56 @@ func doServeAdmitFunc(w http.ResponseWriter, r *http.Request, admit admitFunc, k
 
 	// Step 2: Parse the AdmissionReview request.
 	klog.V(4).Infof("Step 2: Parse the AdmissionReview request.")
-	var admissionReviewReq v1.AdmissionReview
-
-	if _, _, err := universalDeserializer.Decode(body, nil, &admissionReviewReq); err != nil {
+	request, apiVersion, err := decodeAdmissionReview(body)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return nil, fmt.Errorf("could not deserialize request: %v", err)
-	} else if admissionReviewReq.Request == nil {
+	} else if request == nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return nil, errors.New("malformed admission review: request is nil")
 	}
 
-	// Step 3: Construct the AdmissionReview response.
+	// Step 3: Construct the AdmissionReview response in the version of the request.
 	klog.V(4).Infof("Step 3: Construct the AdmissionReview response.")
-	admissionReviewResponse := v1.AdmissionReview{
-		Response: &v1.AdmissionResponse{
-			UID: admissionReviewReq.Request.UID,
-		},
+	response := &v1.AdmissionResponse{
+		UID: request.UID,
 	}
 	var patchOps []patchOperation
-	patchOps, err = admit(admissionReviewReq.Request, k8sClient)
+	var warnings []string
+	patchOps, warnings, err = review(request, k8sClient)
 	if err != nil {
 		// If the handler returned an error, incorporate the error message into the response and deny the object
 		// creation.
-		admissionReviewResponse.Response.Allowed = false
-		admissionReviewResponse.Response.Result = &metav1.Status{
+		response.Allowed = false
+		response.Result = &metav1.Status{
 			Message: err.Error(),
 		}
 	} else {
 		// Otherwise, encode the patch operations to JSON and return a positive response.
-		patchBytes, err := json.Marshal(patchOps)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return nil, fmt.Errorf("could not marshal JSON patch: %v", err)
+		response.Allowed = true
+		if len(warnings) > 0 {
+			// AdmissionResponse has no warnings field before Kubernetes v1.19, they are recorded in the audit log.
+			klog.Warningf("Allow %s %s/%s with warnings: %s", request.Kind.Kind, request.Namespace, request.Name, strings.Join(warnings, "; "))
+			response.AuditAnnotations = map[string]string{
+				"warning": strings.Join(warnings, "; "),
+			}
+		}
+		if len(patchOps) > 0 {
+			patchBytes, err := json.Marshal(patchOps)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return nil, fmt.Errorf("could not marshal JSON patch: %v", err)
+			}
+			patchType := v1.PatchTypeJSONPatch
+			response.Patch = patchBytes
+			response.PatchType = &patchType
 		}
-		admissionReviewResponse.Response.Allowed = true
-		admissionReviewResponse.Response.Patch = patchBytes
 	}
 
+	recordAdmission(r.URL.Path, string(request.Operation), response.Allowed)
+
 	// Return the AdmissionReview with a response as JSON.
-	bytes, err := json.Marshal(&admissionReviewResponse)
+	bytes, err := encodeAdmissionReview(apiVersion, response)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling response: %v", err)
 	}
@@ -131,11 +153,14 @@ func doServeAdmitFunc(w http.ResponseWriter, r *http.Request, admit admitFunc, k
 }
 
 // serveAdmitFunc is a wrapper around doServeAdmitFunc that adds error handling and logging.
-func serveAdmitFunc(w http.ResponseWriter, r *http.Request, admit admitFunc, k8sclient kubernetes.Interface) {
+func serveAdmitFunc(w http.ResponseWriter, r *http.Request, review reviewFunc, k8sclient kubernetes.Interface) {
 	klog.Info("Handling webhook request ...")
+	start := time.Now()
 	var writeErr error
-	if bytes, err := doServeAdmitFunc(w, r, admit, k8sclient); err != nil {
-		klog.Info("Error handling webhook request: %v", err)
+	bytes, err := doServeAdmitFunc(w, r, review, k8sclient)
+	recordAdmissionDuration(r.URL.Path, err, start)
+	if err != nil {
+		klog.Errorf("Error handling webhook request: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		_, writeErr = w.Write([]byte(err.Error()))
 	} else {
@@ -144,19 +169,34 @@ func serveAdmitFunc(w http.ResponseWriter, r *http.Request, admit admitFunc, k8s
 	}
 
 	if writeErr != nil {
-		klog.Info("Could not write response: %v", writeErr)
+		klog.Errorf("Could not write response: %v", writeErr)
 	}
 }
 
 func AdmitFuncHandler(admit admitFunc, k8sClient kubernetes.Interface) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		serveAdmitFunc(w, r, admit, k8sClient)
+		serveAdmitFunc(w, r, func(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]patchOperation, []string, error) {
+			patches, err := admit(req, k8sClient)
+			return patches, nil, err
+		}, k8sClient)
 	})
 }
 
-func AddSidecarContainer(req *v1.AdmissionRequest, k8sclient kubernetes.Interface) ([]patchOperation, error) {
+// SidecarInjector injects the sidecar container into CSI controller pods.
+type SidecarInjector struct {
+	config *SidecarInjectionConfigStore
+	// nsLister looks up the injection label of namespaces without calling the API server in the admission path.
+	nsLister corelisters.NamespaceLister
+}
+
+// NewSidecarInjector returns a SidecarInjector using the current config of the store.
+func NewSidecarInjector(config *SidecarInjectionConfigStore, nsInformer coreinformers.NamespaceInformer) *SidecarInjector {
+	return &SidecarInjector{config: config, nsLister: nsInformer.Lister()}
+}
+
+func (i *SidecarInjector) AddSidecarContainer(req *v1.AdmissionRequest, k8sclient kubernetes.Interface) ([]patchOperation, error) {
 	if req.Resource != podResource {
-		klog.Info("expect resource to be %s", podResource)
+		klog.Infof("expect resource to be %s", podResource)
 		return nil, nil
 	}
 
@@ -170,33 +210,99 @@ func AddSidecarContainer(req *v1.AdmissionRequest, k8sclient kubernetes.Interfac
 	klog.V(4).Infof("Handle pod %s", pod.String())
 	// Retrieve labels
 	addr, volName, mountPath := retrieveAnnotations(pod.GetAnnotations())
+	if addr == "" || volName == "" || mountPath == "" {
+		if !injectionEnabled(i.nsLister, req.Namespace, pod.GetAnnotations()) {
+			return nil, nil
+		}
+		// The annotations override the detected socket.
+		var err error
+		addr, volName, mountPath, err = detectCSISocket(&pod, addr, volName, mountPath)
+		if err != nil {
+			klog.Warningf("Skip pod %s/%s, CSI socket not detected: %s", req.Namespace, pod.GetGenerateName()+pod.GetName(), err)
+			return nil, nil
+		}
+	}
 	klog.V(4).Infof("Addr: %s, VolName: %s, MountPath: %s", addr, volName, mountPath)
+	if addr == "" || volName == "" || mountPath == "" {
+		// not patch
+		return nil, nil
+	}
+	config, err := podSidecarConfig(i.config.Get(), pod.GetAnnotations())
+	if err != nil {
+		klog.Warningf("Skip pod %s/%s: %s", req.Namespace, pod.GetGenerateName()+pod.GetName(), err)
+		return nil, nil
+	}
+	sidecar := getSidecarContainerSpec(config, addr, volName, mountPath)
+	version := sidecarVersion(&sidecar)
+
 	// patches
 	var patches []patchOperation
-	if addr != "" && volName != "" && mountPath != "" {
-		// patch
-		// add container
+	index := findContainer(pod.Spec.Containers, sidecarContainerName)
+	switch {
+	case index < 0:
 		klog.V(4).Infof("Patch add containers")
 		patches = append(patches, patchOperation{
 			Op:   "add",
 			Path: "/spec/containers/-",
 			// The value must not be true if runAsUser is set to 0, as otherwise we would create a conflicting
 			// configuration ourselves.
-			Value: getSidecarContainerSpec(addr, volName, mountPath),
+			Value: sidecar,
 		})
-	} else {
-		// not patch
+	case pod.GetLabels()[LabelInjected] != "true":
+		// The sidecar is declared by the pod itself.
+		klog.V(4).Infof("Skip pod %s/%s, container %s already exists", req.Namespace, pod.GetGenerateName()+pod.GetName(), sidecarContainerName)
+		return nil, nil
+	case pod.GetAnnotations()[AnnotationInjectedVersion] == version:
+		// The webhook is reinvoked, or the pod is created from an injected pod of the current version.
+		// Changes made by other webhooks to the sidecar are kept.
+		klog.V(4).Infof("Skip pod %s/%s, sidecar of version %s already injected", req.Namespace, pod.GetGenerateName()+pod.GetName(), version)
 		return nil, nil
+	default:
+		// The pod is created from an injected pod of another version, upgrade its sidecar.
+		klog.V(4).Infof("Patch replace container %s of version %s", sidecarContainerName, pod.GetAnnotations()[AnnotationInjectedVersion])
+		patches = append(patches, patchOperation{
+			Op:    "replace",
+			Path:  fmt.Sprintf("/spec/containers/%d", index),
+			Value: sidecar,
+		})
 	}
-
-	// Create RBAC
-	klog.V(4).Infof("Create ClusterRoleBinding %s-in-%s", pod.Spec.ServiceAccountName, req.Namespace)
-	if err := AddClusterRoleBinding(k8sclient, pod.Spec.ServiceAccountName, req.Namespace); err != nil {
-		klog.Errorf("Add ClusterRoleBinding error: %s", err)
-		return nil, err
+	// The label lets RBACReconciler bind the ClusterRole to the service account of the pod. RBAC is not
+	// created here, because the request may be a dry-run or denied by a later admission webhook.
+	if pod.GetLabels()[LabelInjected] != "true" {
+		patches = append(patches, addMetadataPatch("labels", pod.GetLabels(), LabelInjected, "true"))
 	}
+	patches = append(patches, addMetadataPatch("annotations", pod.GetAnnotations(), AnnotationInjectedVersion, version))
 	return patches, nil
-	//return nil, nil
+}
+
+// addMetadataPatch returns the patch operation adding the key to the labels or annotations of an object.
+// An existing key is replaced.
+func addMetadataPatch(field string, existing map[string]string, key string, value string) patchOperation {
+	if existing == nil {
+		return patchOperation{Op: "add", Path: "/metadata/" + field, Value: map[string]string{key: value}}
+	}
+	// "~" and "/" are escaped in JSON pointers, see https://tools.ietf.org/html/rfc6901 .
+	escaped := strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+	return patchOperation{Op: "add", Path: "/metadata/" + field + "/" + escaped, Value: value}
+}
+
+// findContainer returns the index of the container with the name, or -1 if not found.
+func findContainer(containers []corev1.Container, name string) int {
+	for i := range containers {
+		if containers[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// sidecarVersion returns the hash of the sidecar container spec, which changes with the image and the config.
+func sidecarVersion(sidecar *corev1.Container) string {
+	// Keys of maps are sorted by json, so the hash is stable.
+	data, _ := json.Marshal(sidecar)
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
 }
 
 func retrieveAnnotations(annotations map[string]string) (address, volumeName, mountPath string) {
@@ -213,21 +319,36 @@ func retrieveAnnotations(annotations map[string]string) (address, volumeName, mo
 	return address, volumeName, mountPath
 }
 
-func getSidecarContainerSpec(addr, volName, mountPath string) corev1.Container {
+func getSidecarContainerSpec(config *SidecarInjectionConfig, addr, volName, mountPath string) corev1.Container {
+	args := []string{
+		"--csi-address=$(ADDRESS)",
+		"--leader-election",
+	}
+	if config.ResyncPeriod != nil {
+		args = append(args, "--resync-period="+config.ResyncPeriod.Duration.String())
+	}
+	if config.Timeout != nil {
+		args = append(args, "--timeout="+config.Timeout.Duration.String())
+	}
 	return corev1.Container{
-		Args: []string{
-			"--csi-address=$(ADDRESS)",
-			"--v=5",
-		},
-		Env: []corev1.EnvVar{
+		Args: append(args, config.Args...),
+		Env: mergeEnv([]corev1.EnvVar{
 			{
 				Name:  "ADDRESS",
 				Value: addr,
 			},
-		},
-		Name:            "storage-capability",
-		Image:           storageCapabilityImage,
-		ImagePullPolicy: corev1.PullAlways,
+			{
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+				},
+			},
+		}, config.Env),
+		Name:            sidecarContainerName,
+		Image:           config.Image,
+		ImagePullPolicy: config.ImagePullPolicy,
+		Resources:       *config.Resources.DeepCopy(),
+		SecurityContext: config.SecurityContext.DeepCopy(),
 		VolumeMounts: []corev1.VolumeMount{
 			{Name: volName, MountPath: mountPath},
 		},
@@ -235,8 +356,8 @@ func getSidecarContainerSpec(addr, volName, mountPath string) corev1.Container {
 }
 
 func AddClusterRoleBinding(k8sclient kubernetes.Interface, sa string, ns string) error {
-	// If cannot find ClusterRole, create it.
-	if err := createSidecarClusterRole(k8sclient, []byte(clusterRole)); err != nil {
+	// Create the ClusterRole, or update it to the rules of this version.
+	if err := applySidecarClusterRole(k8sclient); err != nil {
 		return err
 	}
 	// Create ClusterRoleBinding
@@ -245,49 +366,120 @@ func AddClusterRoleBinding(k8sclient kubernetes.Interface, sa string, ns string)
 		ServiceAccountName      string
 		ServiceAccountNamespace string
 	}{
-		UniqueName:              sa + "-in-" + ns,
+		UniqueName:              clusterRoleBindingName(sa, ns),
 		ServiceAccountName:      sa,
 		ServiceAccountNamespace: ns,
 	})
 	if err != nil {
 		return pkgerrors.Wrap(err, "error when parsing Sidecar ClusterRoleBinding template")
 	}
-	if err := createSidecarClusterRoleBinding(k8sclient, clusterRoleBindingBytes); err != nil {
+	if err := applySidecarClusterRoleBinding(k8sclient, clusterRoleBindingBytes); err != nil {
 		return err
 	}
 	return nil
 }
 
-func createSidecarClusterRole(client kubernetes.Interface, clusterRoleBytes []byte) error {
+// applySidecarClusterRole creates the sidecar ClusterRole, or updates its labels and rules if they differ
+// from the template.
+func applySidecarClusterRole(client kubernetes.Interface) error {
 	clusterRoleEntity := &rbacv1.ClusterRole{}
-	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), clusterRoleBytes, clusterRoleEntity); err != nil {
+	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), []byte(clusterRole), clusterRoleEntity); err != nil {
 		return pkgerrors.Wrap(err, "unable to decode sidecar clusterrole")
 	}
-	if _, err := client.RbacV1().ClusterRoles().Create(clusterRoleEntity); err != nil {
-		if k8serrors.IsAlreadyExists(err) {
-			klog.V(4).Infof("ClusterRole %s already exist", clusterRoleName)
-			return nil
-		}
+	_, err := client.RbacV1().ClusterRoles().Create(clusterRoleEntity)
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
 		return pkgerrors.Wrapf(err, "create ClusterRole %s error", clusterRoleName)
 	}
+	existing, err := client.RbacV1().ClusterRoles().Get(clusterRoleName, metav1.GetOptions{})
+	if err != nil {
+		return pkgerrors.Wrapf(err, "get ClusterRole %s error", clusterRoleName)
+	}
+	if equality.Semantic.DeepEqual(existing.Rules, clusterRoleEntity.Rules) && labelsContain(existing.GetLabels(), clusterRoleEntity.GetLabels()) {
+		klog.V(4).Infof("ClusterRole %s already exist", clusterRoleName)
+		return nil
+	}
+	updated := existing.DeepCopy()
+	updated.Rules = clusterRoleEntity.Rules
+	updated.Labels = mergeLabels(updated.Labels, clusterRoleEntity.GetLabels())
+	if _, err := client.RbacV1().ClusterRoles().Update(updated); err != nil {
+		return pkgerrors.Wrapf(err, "update ClusterRole %s error", clusterRoleName)
+	}
+	klog.Infof("ClusterRole %s updated", clusterRoleName)
 	return nil
 }
 
-func createSidecarClusterRoleBinding(client kubernetes.Interface, clusterRoleBindingBytes []byte) error {
+// applySidecarClusterRoleBinding creates the ClusterRoleBinding, or restores it if it differs from the template.
+// The roleRef of a binding is immutable, so a binding referring to another role is recreated.
+func applySidecarClusterRoleBinding(client kubernetes.Interface, clusterRoleBindingBytes []byte) error {
 	clusterRoleBindingEntity := &rbacv1.ClusterRoleBinding{}
 	if err := kuberuntime.DecodeInto(clientsetscheme.Codecs.UniversalDecoder(), clusterRoleBindingBytes, clusterRoleBindingEntity); err != nil {
 		return pkgerrors.Wrap(err, "unable to decode sidecar ClusterRoleBinding")
 	}
-	if _, err := client.RbacV1().ClusterRoleBindings().Create(clusterRoleBindingEntity); err != nil {
-		if k8serrors.IsAlreadyExists(err) {
-			klog.V(4).Infof("ClusterRoleBinding %s already exist", clusterRoleBindingEntity.GetName())
-			return nil
+	name := clusterRoleBindingEntity.GetName()
+	_, err := client.RbacV1().ClusterRoleBindings().Create(clusterRoleBindingEntity)
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return pkgerrors.Wrapf(err, "create ClusterRoleBinding %s error", name)
+	}
+	existing, err := client.RbacV1().ClusterRoleBindings().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return pkgerrors.Wrapf(err, "get ClusterRoleBinding %s error", name)
+	}
+	if existing.RoleRef != clusterRoleBindingEntity.RoleRef {
+		klog.Infof("ClusterRoleBinding %s refers to %s %s, recreate it", name, existing.RoleRef.Kind, existing.RoleRef.Name)
+		err := client.RbacV1().ClusterRoleBindings().Delete(name, &metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &existing.UID},
+		})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return pkgerrors.Wrapf(err, "delete ClusterRoleBinding %s error", name)
+		}
+		if _, err := client.RbacV1().ClusterRoleBindings().Create(clusterRoleBindingEntity); err != nil {
+			return pkgerrors.Wrapf(err, "create ClusterRoleBinding %s error", name)
 		}
-		return pkgerrors.Wrapf(err, "create ClusterRoleBinding %s error", clusterRoleBindingEntity.GetName())
+		return nil
+	}
+	if equality.Semantic.DeepEqual(existing.Subjects, clusterRoleBindingEntity.Subjects) &&
+		labelsContain(existing.GetLabels(), clusterRoleBindingEntity.GetLabels()) {
+		klog.V(4).Infof("ClusterRoleBinding %s already exist", name)
+		return nil
+	}
+	updated := existing.DeepCopy()
+	updated.Subjects = clusterRoleBindingEntity.Subjects
+	updated.Labels = mergeLabels(updated.Labels, clusterRoleBindingEntity.GetLabels())
+	if _, err := client.RbacV1().ClusterRoleBindings().Update(updated); err != nil {
+		return pkgerrors.Wrapf(err, "update ClusterRoleBinding %s error", name)
 	}
+	klog.Infof("ClusterRoleBinding %s updated", name)
 	return nil
 }
 
+// labelsContain tells whether labels has all the expected labels.
+func labelsContain(labels map[string]string, expected map[string]string) bool {
+	for k, v := range expected {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeLabels returns the labels overwritten by the given ones.
+func mergeLabels(labels map[string]string, overwrite map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(overwrite))
+	for k, v := range labels {
+		merged[k] = v
+	}
+	for k, v := range overwrite {
+		merged[k] = v
+	}
+	return merged
+}
+
 // ParseTemplate validates and parses passed as argument template
 func parseTemplate(strtmpl string, obj interface{}) ([]byte, error) {
 	var buf bytes.Buffer
@@ -0,0 +1,411 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"k8s.io/api/admission/v1"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// reviewedResponse is the response of an AdmissionReview of any version.
+type reviewedResponse struct {
+	metav1.TypeMeta `json:",inline"`
+	Response        *struct {
+		UID              types.UID         `json:"uid"`
+		Allowed          bool              `json:"allowed"`
+		Result           *metav1.Status    `json:"status,omitempty"`
+		Patch            []byte            `json:"patch,omitempty"`
+		PatchType        *string           `json:"patchType,omitempty"`
+		AuditAnnotations map[string]string `json:"auditAnnotations,omitempty"`
+	} `json:"response"`
+}
+
+func newAdmissionReviewBody(t *testing.T, apiVersion string, operation string) []byte {
+	request := map[string]interface{}{
+		"uid":       "705ab4f5-6393-11e8-b7cc-42010a800002",
+		"kind":      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		"resource":  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+		"namespace": "default",
+		"name":      "csi-controller",
+		"operation": operation,
+		"object":    runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Pod"}`)},
+	}
+	review := map[string]interface{}{"apiVersion": apiVersion, "kind": "AdmissionReview", "request": request}
+	if operation == "" {
+		delete(review, "request")
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestServeAdmitFunc(t *testing.T) {
+	patchReview := func(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]patchOperation, []string, error) {
+		if req.Operation != v1.Create {
+			return nil, nil, errors.New("unexpected operation " + string(req.Operation))
+		}
+		return []patchOperation{{Op: "add", Path: "/metadata/labels", Value: map[string]string{"a": "b"}}}, nil, nil
+	}
+	warnReview := func(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]patchOperation, []string, error) {
+		return nil, []string{"offline expansion"}, nil
+	}
+	denyReview := func(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]patchOperation, []string, error) {
+		return nil, nil, errors.New("denied")
+	}
+	expectedPatch := `[{"op":"add","path":"/metadata/labels","value":{"a":"b"}}]`
+	tests := []struct {
+		name        string
+		apiVersion  string
+		operation   string
+		contentType string
+		review      reviewFunc
+		// Fields of the response, checked if the status is OK.
+		expectedStatus      int
+		expectedAllowed     bool
+		expectedPatch       string
+		expectedMessage     string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:            "v1 patch",
+			apiVersion:      v1.SchemeGroupVersion.String(),
+			operation:       "CREATE",
+			review:          patchReview,
+			expectedStatus:  http.StatusOK,
+			expectedAllowed: true,
+			expectedPatch:   expectedPatch,
+		},
+		{
+			name:            "v1beta1 patch",
+			apiVersion:      v1beta1.SchemeGroupVersion.String(),
+			operation:       "CREATE",
+			review:          patchReview,
+			expectedStatus:  http.StatusOK,
+			expectedAllowed: true,
+			expectedPatch:   expectedPatch,
+		},
+		{
+			name:                "v1 warning",
+			apiVersion:          v1.SchemeGroupVersion.String(),
+			operation:           "UPDATE",
+			review:              warnReview,
+			expectedStatus:      http.StatusOK,
+			expectedAllowed:     true,
+			expectedAnnotations: map[string]string{"warning": "offline expansion"},
+		},
+		{
+			name:                "v1beta1 warning",
+			apiVersion:          v1beta1.SchemeGroupVersion.String(),
+			operation:           "UPDATE",
+			review:              warnReview,
+			expectedStatus:      http.StatusOK,
+			expectedAllowed:     true,
+			expectedAnnotations: map[string]string{"warning": "offline expansion"},
+		},
+		{
+			name:            "v1 deny",
+			apiVersion:      v1.SchemeGroupVersion.String(),
+			operation:       "CREATE",
+			review:          denyReview,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "denied",
+		},
+		{
+			name:            "v1beta1 deny",
+			apiVersion:      v1beta1.SchemeGroupVersion.String(),
+			operation:       "CREATE",
+			review:          denyReview,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "denied",
+		},
+		{
+			name:           "unsupported version",
+			apiVersion:     "admission.k8s.io/v2",
+			operation:      "CREATE",
+			review:         patchReview,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing request",
+			apiVersion:     v1.SchemeGroupVersion.String(),
+			review:         patchReview,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported content type",
+			apiVersion:     v1.SchemeGroupVersion.String(),
+			operation:      "CREATE",
+			contentType:    "application/yaml",
+			review:         patchReview,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(newAdmissionReviewBody(t, test.apiVersion, test.operation)))
+			contentType := test.contentType
+			if contentType == "" {
+				contentType = jsonContentType
+			}
+			req.Header.Set("Content-Type", contentType)
+			recorder := httptest.NewRecorder()
+			serveAdmitFunc(recorder, req, test.review, k8sfake.NewSimpleClientset())
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+			got := reviewedResponse{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			if got.APIVersion != test.apiVersion || got.Kind != "AdmissionReview" {
+				t.Errorf("expected AdmissionReview %s, got %s %s", test.apiVersion, got.Kind, got.APIVersion)
+			}
+			if got.Response == nil {
+				t.Fatal("response is nil")
+			}
+			if got.Response.UID != "705ab4f5-6393-11e8-b7cc-42010a800002" {
+				t.Errorf("unexpected uid %s", got.Response.UID)
+			}
+			if got.Response.Allowed != test.expectedAllowed {
+				t.Errorf("expected allowed %v, got %v", test.expectedAllowed, got.Response.Allowed)
+			}
+			if string(got.Response.Patch) != test.expectedPatch {
+				t.Errorf("expected patch %s, got %s", test.expectedPatch, got.Response.Patch)
+			}
+			if test.expectedPatch == "" && got.Response.PatchType != nil {
+				t.Errorf("expected no patchType, got %s", *got.Response.PatchType)
+			}
+			if test.expectedPatch != "" && (got.Response.PatchType == nil || *got.Response.PatchType != string(v1.PatchTypeJSONPatch)) {
+				t.Errorf("expected patchType %s, got %v", v1.PatchTypeJSONPatch, got.Response.PatchType)
+			}
+			message := ""
+			if got.Response.Result != nil {
+				message = got.Response.Result.Message
+			}
+			if message != test.expectedMessage {
+				t.Errorf("expected message %q, got %q", test.expectedMessage, message)
+			}
+			if !reflect.DeepEqual(got.Response.AuditAnnotations, test.expectedAnnotations) {
+				t.Errorf("expected audit annotations %v, got %v", test.expectedAnnotations, got.Response.AuditAnnotations)
+			}
+		})
+	}
+}
+
+// newTestSidecarInjector returns a SidecarInjector whose namespace cache holds the namespaces.
+func newTestSidecarInjector(namespaces ...*corev1.Namespace) *SidecarInjector {
+	nsInformer := kubeinformers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0).Core().V1().Namespaces()
+	for _, ns := range namespaces {
+		nsInformer.Informer().GetIndexer().Add(ns)
+	}
+	return NewSidecarInjector(NewSidecarInjectionConfigStore(), nsInformer)
+}
+
+// failingNamespaceLister fails every lookup, like a cache which is not available.
+type failingNamespaceLister struct {
+	corelisters.NamespaceLister
+}
+
+func (l failingNamespaceLister) Get(name string) (*corev1.Namespace, error) {
+	return nil, errors.New("cache not available")
+}
+
+func TestInjectionEnabled(t *testing.T) {
+	enabledNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "enabled",
+		Labels: map[string]string{labelInjection: "enabled"},
+	}}
+	plainNamespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "plain"}}
+	nsLister := newTestSidecarInjector(enabledNamespace, plainNamespace).nsLister
+	tests := []struct {
+		name        string
+		nsLister    corelisters.NamespaceLister
+		namespace   string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:      "namespace enabled",
+			nsLister:  nsLister,
+			namespace: "enabled",
+			expected:  true,
+		},
+		{
+			name:      "namespace not labeled",
+			nsLister:  nsLister,
+			namespace: "plain",
+		},
+		{
+			name:      "namespace not found",
+			nsLister:  nsLister,
+			namespace: "missing",
+		},
+		{
+			name:      "namespace lookup failed",
+			nsLister:  failingNamespaceLister{},
+			namespace: "enabled",
+		},
+		{
+			name:        "annotation opts in",
+			nsLister:    failingNamespaceLister{},
+			namespace:   "plain",
+			annotations: map[string]string{annotationInject: "true"},
+			expected:    true,
+		},
+		{
+			name:        "annotation opts out",
+			nsLister:    nsLister,
+			namespace:   "enabled",
+			annotations: map[string]string{annotationInject: "false"},
+		},
+		{
+			name:        "invalid annotation",
+			nsLister:    nsLister,
+			namespace:   "enabled",
+			annotations: map[string]string{annotationInject: "yes please"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if enabled := injectionEnabled(test.nsLister, test.namespace, test.annotations); enabled != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, enabled)
+			}
+		})
+	}
+}
+
+func TestAddSidecarContainer(t *testing.T) {
+	injector := newTestSidecarInjector()
+	annotations := map[string]string{
+		annotationAddress:    "/csi/csi.sock",
+		annotationVolumeName: "socket-dir",
+		annotationMountPath:  "/csi",
+	}
+	sidecar := getSidecarContainerSpec(DefaultSidecarInjectionConfig(), "/csi/csi.sock", "socket-dir", "/csi")
+	version := sidecarVersion(&sidecar)
+	oldSidecar := *sidecar.DeepCopy()
+	oldSidecar.Image = "kubespheredev/storage-capability-sidecar:v0.0.1"
+
+	newPod := func(injected bool, injectedVersion string, containers ...corev1.Container) *corev1.Pod {
+		pod := &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "csi-controller", Namespace: "default", Annotations: map[string]string{}},
+			Spec:       corev1.PodSpec{Containers: append([]corev1.Container{{Name: "csi-provisioner"}}, containers...)},
+		}
+		for k, v := range annotations {
+			pod.Annotations[k] = v
+		}
+		if injected {
+			pod.Labels = map[string]string{LabelInjected: "true"}
+		}
+		if injectedVersion != "" {
+			pod.Annotations[AnnotationInjectedVersion] = injectedVersion
+		}
+		return pod
+	}
+	labelPatch := patchOperation{Op: "add", Path: "/metadata/labels", Value: map[string]string{LabelInjected: "true"}}
+	versionPatch := patchOperation{Op: "add", Path: "/metadata/annotations/storage.kubesphere.io~1storage-capability-injected-version", Value: version}
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected []patchOperation
+	}{
+		{
+			name:     "inject",
+			pod:      newPod(false, ""),
+			expected: []patchOperation{{Op: "add", Path: "/spec/containers/-", Value: sidecar}, labelPatch, versionPatch},
+		},
+		{
+			name:     "reinvoked",
+			pod:      newPod(true, version, sidecar),
+			expected: nil,
+		},
+		{
+			name:     "declared by the pod",
+			pod:      newPod(false, "", corev1.Container{Name: sidecarContainerName}),
+			expected: nil,
+		},
+		{
+			name:     "upgrade",
+			pod:      newPod(true, sidecarVersion(&oldSidecar), oldSidecar),
+			expected: []patchOperation{{Op: "replace", Path: "/spec/containers/1", Value: sidecar}, versionPatch},
+		},
+		{
+			name: "invalid sidecar config",
+			pod: func() *corev1.Pod {
+				pod := newPod(false, "")
+				pod.Annotations[annotationSidecarConfig] = `{"image": `
+				return pod
+			}(),
+			expected: nil,
+		},
+		{
+			name: "invalid inject annotation",
+			pod: func() *corev1.Pod {
+				pod := newPod(false, "")
+				delete(pod.Annotations, annotationAddress)
+				pod.Annotations[annotationInject] = "yes please"
+				return pod
+			}(),
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := json.Marshal(test.pod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &v1.AdmissionRequest{
+				Resource:  podResource,
+				Namespace: "default",
+				Operation: v1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			}
+			patches, err := injector.AddSidecarContainer(req, k8sfake.NewSimpleClientset())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(patches, test.expected) {
+				t.Errorf("expected patches %+v, got %+v", test.expected, patches)
+			}
+		})
+	}
+}
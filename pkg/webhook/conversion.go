@@ -0,0 +1,182 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1beta1"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog"
+	"net/http"
+)
+
+var conversionScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(v1alpha1.AddToScheme(conversionScheme))
+	utilruntime.Must(v1beta1.AddToScheme(conversionScheme))
+}
+
+// conversionReview mirrors ConversionReview of apiextensions.k8s.io. The v1 and v1beta1 versions
+// share the same layout, so one type serves both.
+type conversionReview struct {
+	metav1.TypeMeta `json:",inline"`
+	Request         *conversionRequest  `json:"request,omitempty"`
+	Response        *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               types.UID              `json:"uid"`
+	DesiredAPIVersion string                 `json:"desiredAPIVersion"`
+	Objects           []runtime.RawExtension `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              types.UID              `json:"uid"`
+	ConvertedObjects []runtime.RawExtension `json:"convertedObjects"`
+	Result           metav1.Status          `json:"result"`
+}
+
+// doServeConvert parses the ConversionReview sent by the API server and converts every object.
+func doServeConvert(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, fmt.Errorf("invalid method %s, only POST requests are allowed", r.Method)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, fmt.Errorf("could not read request body: %v", err)
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != jsonContentType {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, fmt.Errorf("unsupported content type %s, only %s is supported", contentType, jsonContentType)
+	}
+
+	var review conversionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, fmt.Errorf("could not deserialize request: %v", err)
+	} else if review.Request == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, errors.New("malformed conversion review: request is nil")
+	}
+
+	// Respond in the same version of ConversionReview as the request.
+	response := conversionReview{
+		TypeMeta: review.TypeMeta,
+		Response: &conversionResponse{
+			UID: review.Request.UID,
+		},
+	}
+	converted, err := convertObjects(review.Request.Objects, review.Request.DesiredAPIVersion)
+	if err != nil {
+		klog.Errorf("Convert objects to %s error: %s", review.Request.DesiredAPIVersion, err)
+		response.Response.Result = metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+		}
+	} else {
+		response.Response.ConvertedObjects = converted
+		response.Response.Result = metav1.Status{
+			Status: metav1.StatusSuccess,
+		}
+	}
+
+	bytes, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling response: %v", err)
+	}
+	return bytes, nil
+}
+
+func convertObjects(objects []runtime.RawExtension, desiredAPIVersion string) ([]runtime.RawExtension, error) {
+	desiredGV, err := schema.ParseGroupVersion(desiredAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]runtime.RawExtension, 0, len(objects))
+	for _, obj := range objects {
+		res, err := convertObject(obj.Raw, desiredGV)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, runtime.RawExtension{Raw: res})
+	}
+	return converted, nil
+}
+
+// convertObject converts a JSON encoded ProvisionerCapability or StorageClassCapability to desired version.
+func convertObject(raw []byte, desiredGV schema.GroupVersion) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(raw, &typeMeta); err != nil {
+		return nil, fmt.Errorf("could not get type of object: %v", err)
+	}
+	srcGVK := typeMeta.GroupVersionKind()
+	if srcGVK.GroupVersion() == desiredGV {
+		return raw, nil
+	}
+	src, err := conversionScheme.New(srcGVK)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, src); err != nil {
+		return nil, fmt.Errorf("could not deserialize %s: %v", srcGVK, err)
+	}
+	desiredGVK := desiredGV.WithKind(srcGVK.Kind)
+	dst, err := conversionScheme.New(desiredGVK)
+	if err != nil {
+		return nil, err
+	}
+	if err := conversionScheme.Convert(src, dst, nil); err != nil {
+		return nil, fmt.Errorf("could not convert %s to %s: %v", srcGVK, desiredGVK, err)
+	}
+	dst.GetObjectKind().SetGroupVersionKind(desiredGVK)
+	return json.Marshal(dst)
+}
+
+// ConvertHandler serves CRD conversion requests of the storage.kubesphere.io group.
+func ConvertHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		klog.Info("Handling conversion request ...")
+		var writeErr error
+		if bytes, err := doServeConvert(w, r); err != nil {
+			klog.Errorf("Error handling conversion request: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			_, writeErr = w.Write([]byte(err.Error()))
+		} else {
+			klog.Info("Conversion request handled successfully")
+			_, writeErr = w.Write(bytes)
+		}
+
+		if writeErr != nil {
+			klog.Errorf("Could not write response: %v", writeErr)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// annotationInject opts a pod in or out of injection with the CSI socket detected from the pod.
+	annotationInject = "storage.kubesphere.io/storage-capability-inject"
+	// labelInjection set to "enabled" on a namespace opts in all pods of the namespace.
+	labelInjection = "storage.kubesphere.io/storage-capability-injection"
+)
+
+// csiSidecarNames are the containers talking to the CSI controller socket, in order of preference.
+var csiSidecarNames = []string{"csi-provisioner", "csi-attacher"}
+
+// injectionEnabled tells whether the CSI socket should be detected for the pod. The pod annotation wins,
+// otherwise the label of the namespace decides. Injection is skipped if the annotation is invalid or the
+// namespace can not be looked up, so the webhook never blocks the creation of pods.
+func injectionEnabled(nsLister corelisters.NamespaceLister, namespace string, annotations map[string]string) bool {
+	if value, ok := annotations[annotationInject]; ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			klog.Warningf("Skip injection, invalid annotation %s=%q of pod in namespace %s", annotationInject, value, namespace)
+			return false
+		}
+		return enabled
+	}
+	ns, err := nsLister.Get(namespace)
+	if k8serrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		klog.Errorf("Skip injection, get namespace %s error: %s", namespace, err)
+		return false
+	}
+	return ns.GetLabels()[labelInjection] == "enabled"
+}
+
+// detectCSISocket fills the address, volume name and mount path not given by annotations from the
+// csi-provisioner or csi-attacher container of the pod.
+func detectCSISocket(pod *corev1.Pod, address, volumeName, mountPath string) (string, string, string, error) {
+	container := findCSISidecar(pod)
+	if container == nil {
+		return "", "", "", fmt.Errorf("none of the containers %v found", csiSidecarNames)
+	}
+	if address == "" {
+		address = containerCSIAddress(container)
+		if address == "" {
+			return "", "", "", fmt.Errorf("CSI address of container %s not found", container.Name)
+		}
+	}
+	if volumeName == "" || mountPath == "" {
+		mount := findSocketMount(container, address)
+		if mount == nil {
+			return "", "", "", fmt.Errorf("volume mount of CSI address %s not found in container %s", address, container.Name)
+		}
+		if volumeName == "" {
+			volumeName = mount.Name
+		}
+		if mountPath == "" {
+			mountPath = mount.MountPath
+		}
+	}
+	return address, volumeName, mountPath, nil
+}
+
+// findCSISidecar returns the first container whose name or image matches csiSidecarNames.
+func findCSISidecar(pod *corev1.Pod) *corev1.Container {
+	for _, name := range csiSidecarNames {
+		for i := range pod.Spec.Containers {
+			c := &pod.Spec.Containers[i]
+			if c.Name == name || imageName(c.Image) == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// imageName returns the last path element of the image without tag and digest.
+func imageName(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	name := path.Base(image)
+	return strings.SplitN(name, ":", 2)[0]
+}
+
+// containerCSIAddress reads --csi-address of the container, or its ADDRESS environment variable.
+// Variable references like $(ADDRESS) are expanded with the literal values of the container environment.
+func containerCSIAddress(c *corev1.Container) string {
+	env := map[string]string{}
+	for _, e := range c.Env {
+		if e.ValueFrom == nil {
+			env[e.Name] = e.Value
+		}
+	}
+	args := append(append([]string(nil), c.Command...), c.Args...)
+	for i, arg := range args {
+		for _, flag := range []string{"--csi-address", "-csi-address"} {
+			if strings.HasPrefix(arg, flag+"=") {
+				return expandEnv(strings.TrimPrefix(arg, flag+"="), env)
+			}
+			if arg == flag && i+1 < len(args) {
+				return expandEnv(args[i+1], env)
+			}
+		}
+	}
+	return env["ADDRESS"]
+}
+
+func expandEnv(value string, env map[string]string) string {
+	for name, v := range env {
+		value = strings.Replace(value, "$("+name+")", v, -1)
+	}
+	return value
+}
+
+// findSocketMount returns the volume mount containing the socket, the longest mount path wins.
+func findSocketMount(c *corev1.Container, address string) *corev1.VolumeMount {
+	socket := path.Clean(strings.TrimPrefix(address, "unix://"))
+	var found *corev1.VolumeMount
+	for i := range c.VolumeMounts {
+		m := &c.VolumeMounts[i]
+		dir := path.Clean(m.MountPath)
+		if socket != dir && !strings.HasPrefix(socket, strings.TrimSuffix(dir, "/")+"/") {
+			continue
+		}
+		if found == nil || len(dir) > len(path.Clean(found.MountPath)) {
+			found = m
+		}
+	}
+	return found
+}
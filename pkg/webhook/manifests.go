@@ -51,6 +51,7 @@ rules:
       - "storage.kubesphere.io"
     resources:
       - provisionercapabilities
+      - nodecapabilities
     verbs:
       - create
       - get
@@ -58,6 +59,38 @@ rules:
       - watch
       - update
       - patch
+  - apiGroups:
+      - "storage.kubesphere.io"
+    resources:
+      - provisionercapabilities/status
+      - nodecapabilities/status
+    verbs:
+      - get
+      - update
+      - patch
+  - apiGroups:
+      - ""
+    resources:
+      - nodes
+    verbs:
+      - get
+  - apiGroups:
+      - "coordination.k8s.io"
+    resources:
+      - leases
+    verbs:
+      - get
+      - watch
+      - list
+      - create
+      - update
+  - apiGroups:
+      - ""
+    resources:
+      - events
+    verbs:
+      - create
+      - patch
 `
 	clusterRoleName = "storage-capability-sidecar"
 )
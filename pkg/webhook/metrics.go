@@ -0,0 +1,92 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"time"
+)
+
+const (
+	metricsNamespace = "storage_capability"
+	metricsSubsystem = "webhook"
+)
+
+var (
+	admissionTotal = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "admission_requests_total",
+			Help:           "Number of admission requests per webhook path and operation.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"webhook", "operation"},
+	)
+	admissionDenials = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "admission_denials_total",
+			Help:           "Number of denied admission requests per webhook path and operation.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"webhook", "operation"},
+	)
+	admissionErrors = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "admission_errors_total",
+			Help:           "Number of admission requests that could not be handled, e.g. malformed AdmissionReviews.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"webhook"},
+	)
+	admissionDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      metricsSubsystem,
+			Name:           "admission_duration_seconds",
+			Help:           "Duration of handling admission requests in seconds.",
+			Buckets:        metrics.DefBuckets,
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"webhook"},
+	)
+)
+
+func init() {
+	legacyregistry.MustRegister(admissionTotal, admissionDenials, admissionErrors, admissionDuration)
+}
+
+func recordAdmission(webhook string, operation string, allowed bool) {
+	admissionTotal.WithLabelValues(webhook, operation).Inc()
+	if !allowed {
+		admissionDenials.WithLabelValues(webhook, operation).Inc()
+	}
+}
+
+func recordAdmissionDuration(webhook string, err error, start time.Time) {
+	if err != nil {
+		admissionErrors.WithLabelValues(webhook).Inc()
+	}
+	admissionDuration.WithLabelValues(webhook).Observe(time.Since(start).Seconds())
+}
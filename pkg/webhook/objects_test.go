@@ -0,0 +1,185 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	"k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"testing"
+)
+
+// objectBuilder builds the objects of the webhook tests. Namespaced objects live in its namespace and capabilities
+// belong to its provisioner. Tests change the returned objects for anything else.
+type objectBuilder struct {
+	namespace   string
+	provisioner string
+}
+
+// build is the objectBuilder shared by the webhook tests.
+var build = objectBuilder{namespace: "default", provisioner: "csi.example.com"}
+
+// sccapOption changes a StorageClassCapability built by objectBuilder.sccap.
+type sccapOption func(*v1alpha1.StorageClassCapability)
+
+func withProvisioner(provisioner string) sccapOption {
+	return func(sccap *v1alpha1.StorageClassCapability) {
+		sccap.Spec.Provisioner = provisioner
+	}
+}
+
+func withClone(sccap *v1alpha1.StorageClassCapability) {
+	sccap.Spec.Features.Volume.Clone = true
+}
+
+func withSnapshot(sccap *v1alpha1.StorageClassCapability) {
+	sccap.Spec.Features.Snapshot.Create = true
+}
+
+// request returns an AdmissionRequest creating obj, or updating oldObj to obj if oldObj is not nil.
+func (b objectBuilder) request(t *testing.T, resource string, obj runtime.Object, oldObj runtime.Object) *v1.AdmissionRequest {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	req := &v1.AdmissionRequest{
+		Resource:  metav1.GroupVersionResource{Group: gvk.Group, Version: gvk.Version, Resource: resource},
+		Namespace: accessor.GetNamespace(),
+		Operation: v1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+	if oldObj != nil {
+		oldRaw, err := json.Marshal(oldObj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Operation = v1.Update
+		req.OldObject = runtime.RawExtension{Raw: oldRaw}
+	}
+	return req
+}
+
+func (b objectBuilder) pcap(pluginName string, expand v1alpha1.ExpandMode) *v1alpha1.ProvisionerCapability {
+	return &v1alpha1.ProvisionerCapability{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "ProvisionerCapability"},
+		ObjectMeta: metav1.ObjectMeta{Name: b.provisioner},
+		Spec: v1alpha1.ProvisionerCapabilitySpec{
+			PluginInfo: v1alpha1.ProvisionerCapabilitySpecPluginInfo{Name: pluginName},
+			Features: v1alpha1.ProvisionerCapabilitySpecFeatures{
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: expand},
+			},
+		},
+	}
+}
+
+func (b objectBuilder) sccap(name string, expand v1alpha1.ExpandMode, opts ...sccapOption) *v1alpha1.StorageClassCapability {
+	sccap := &v1alpha1.StorageClassCapability{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "StorageClassCapability"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.StorageClassCapabilitySpec{
+			Provisioner: b.provisioner,
+			Features: v1alpha1.StorageClassCapabilitySpecFeatures{
+				Volume: v1alpha1.ProvisionerCapabilitySpecFeaturesVolume{Expand: expand},
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(sccap)
+	}
+	return sccap
+}
+
+func (b objectBuilder) storageClass(name string) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: name},
+		Provisioner: b.provisioner,
+	}
+}
+
+func (b objectBuilder) pvc(name string, storageClass string, size string, dataSource *corev1.TypedLocalObjectReference) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: b.namespace},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			DataSource:       dataSource,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+	}
+}
+
+// volumeSnapshot returns a VolumeSnapshot of the claim, of the default class if className is empty.
+func (b objectBuilder) volumeSnapshot(claimName string, className string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"source": map[string]interface{}{"persistentVolumeClaimName": claimName},
+		},
+	}}
+	obj.SetAPIVersion(snapshot.GroupName + "/" + snapshot.V1)
+	obj.SetKind("VolumeSnapshot")
+	obj.SetNamespace(b.namespace)
+	obj.SetName("snap")
+	if className != "" {
+		unstructured.SetNestedField(obj.Object, className, "spec", "volumeSnapshotClassName")
+	}
+	return obj
+}
+
+func (b objectBuilder) pod(name string, serviceAccount string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: b.namespace},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccount,
+			Containers:         []corev1.Container{{Name: "app"}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+// injectedPod returns a pod of a CSI plugin the sidecar is injected into.
+func (b objectBuilder) injectedPod(name string, serviceAccount string, phase corev1.PodPhase) *corev1.Pod {
+	pod := b.pod(name, serviceAccount, phase)
+	pod.Labels = map[string]string{LabelInjected: "true"}
+	pod.Spec.Containers = []corev1.Container{{Name: "csi-plugin"}, {Name: sidecarContainerName}}
+	return pod
+}
+
+func (b objectBuilder) clusterRoleBinding(serviceAccount string, roleName string, labels map[string]string) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterRoleBindingName(serviceAccount, b.namespace), Labels: labels, UID: "old"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: roleName},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: b.namespace}},
+	}
+}
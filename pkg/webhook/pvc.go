@@ -0,0 +1,156 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"fmt"
+	crdapi "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	crdlisters "github.com/kubesphere/storage-capability/pkg/generated/listers/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+var pvcResource = metav1.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+
+// PVCValidator denies PersistentVolumeClaims using features the StorageClassCapability of their
+// StorageClass does not report. Claims without StorageClassCapability are allowed.
+type PVCValidator struct {
+	sccapLister crdlisters.StorageClassCapabilityLister
+	recorder    record.EventRecorder
+}
+
+// NewPVCValidator returns a PVCValidator recording warnings as events of the claims.
+func NewPVCValidator(sccapLister crdlisters.StorageClassCapabilityLister, k8sClient kubernetes.Interface) *PVCValidator {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+	return &PVCValidator{
+		sccapLister: sccapLister,
+		recorder:    eventBroadcaster.NewRecorder(clientsetscheme.Scheme, corev1.EventSource{Component: "storage-capability-webhook"}),
+	}
+}
+
+// Validate checks the dataSource of a new claim and the size increase of an updated claim.
+func (p *PVCValidator) Validate(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]string, error) {
+	if req.Resource != pvcResource || (req.Operation != v1.Create && req.Operation != v1.Update) {
+		return nil, nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if _, _, err := universalDeserializer.Decode(req.Object.Raw, nil, pvc); err != nil {
+		return nil, fmt.Errorf("could not deserialize PersistentVolumeClaim: %v", err)
+	}
+	// The DefaultStorageClass admission plugin has set the StorageClass of claims without one.
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return nil, nil
+	}
+	sccap, err := p.sccapLister.Get(*pvc.Spec.StorageClassName)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Operation == v1.Create {
+		return nil, validatePVCDataSource(pvc, sccap).ToAggregate()
+	}
+	oldPVC := &corev1.PersistentVolumeClaim{}
+	if _, _, err := universalDeserializer.Decode(req.OldObject.Raw, nil, oldPVC); err != nil {
+		return nil, fmt.Errorf("could not deserialize old PersistentVolumeClaim: %v", err)
+	}
+	newSize := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	oldSize := oldPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+	if newSize.Cmp(oldSize) <= 0 {
+		return nil, nil
+	}
+	storagePath := field.NewPath("spec", "resources", "requests", string(corev1.ResourceStorage))
+	switch sccap.Spec.Features.Volume.Expand {
+	case crdapi.ExpandModeOnline:
+		return nil, nil
+	case crdapi.ExpandModeOffline:
+		pods, err := podsUsingPVC(k8sClient, req.Namespace, pvc.GetName())
+		if err != nil {
+			return nil, err
+		}
+		if len(pods) == 0 {
+			return nil, nil
+		}
+		warning := fmt.Sprintf("StorageClass %s only supports offline expansion, the volume is expanded after pods %v stop using it", sccap.GetName(), pods)
+		// Events are side effects, which must be skipped on dry-run.
+		if req.DryRun == nil || !*req.DryRun {
+			p.recorder.Event(oldPVC, corev1.EventTypeWarning, "OfflineExpansion", warning)
+		}
+		return []string{warning}, nil
+	default:
+		return nil, field.Forbidden(storagePath, fmt.Sprintf("StorageClass %s does not support volume expansion", sccap.GetName()))
+	}
+}
+
+// validatePVCDataSource denies cloning and restoring from snapshot if not supported.
+func validatePVCDataSource(pvc *corev1.PersistentVolumeClaim, sccap *crdapi.StorageClassCapability) field.ErrorList {
+	ds := pvc.Spec.DataSource
+	if ds == nil {
+		return nil
+	}
+	dsPath := field.NewPath("spec", "dataSource")
+	group := ""
+	if ds.APIGroup != nil {
+		group = *ds.APIGroup
+	}
+	switch {
+	case group == "" && ds.Kind == "PersistentVolumeClaim":
+		if !sccap.Spec.Features.Volume.Clone {
+			return field.ErrorList{field.Forbidden(dsPath, fmt.Sprintf("StorageClass %s does not support volume cloning", sccap.GetName()))}
+		}
+	case group == snapshot.GroupName && ds.Kind == "VolumeSnapshot":
+		if !sccap.Spec.Features.Snapshot.Create {
+			return field.ErrorList{field.Forbidden(dsPath, fmt.Sprintf("StorageClass %s does not support volume snapshot", sccap.GetName()))}
+		}
+	}
+	return nil
+}
+
+// podsUsingPVC returns the names of the pods in the namespace which are not terminated and mount the claim.
+func podsUsingPVC(k8sClient kubernetes.Interface, namespace string, claimName string) ([]string, error) {
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "list pods of namespace %s error", namespace)
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for _, vol := range pod.Spec.Volumes {
+			if vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == claimName {
+				names = append(names, pod.GetName())
+				break
+			}
+		}
+	}
+	return names, nil
+}
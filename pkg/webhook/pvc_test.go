@@ -0,0 +1,153 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	crdfake "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/fake"
+	crdinformers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"strings"
+	"testing"
+)
+
+func TestPVCValidator(t *testing.T) {
+	sccapInformer := crdinformers.NewSharedInformerFactory(crdfake.NewSimpleClientset(), 0).Storage().V1alpha1().StorageClassCapabilities()
+	for _, sccap := range []*v1alpha1.StorageClassCapability{
+		build.sccap("unsupported", v1alpha1.ExpandModeUnknown),
+		build.sccap("offline", v1alpha1.ExpandModeOffline, withClone, withSnapshot),
+		build.sccap("online", v1alpha1.ExpandModeOnline, withClone, withSnapshot),
+	} {
+		sccapInformer.Informer().GetIndexer().Add(sccap)
+	}
+	snapshotGroup := snapshot.GroupName
+	cloneSource := &corev1.TypedLocalObjectReference{Kind: "PersistentVolumeClaim", Name: "source"}
+	snapshotSource := &corev1.TypedLocalObjectReference{APIGroup: &snapshotGroup, Kind: "VolumeSnapshot", Name: "snap"}
+	runningPod := build.pod("app", "", corev1.PodRunning)
+	runningPod.Spec.Volumes = []corev1.Volume{{
+		Name:         "data",
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "pvc"}},
+	}}
+	dryRun := true
+	tests := []struct {
+		name          string
+		pvc           *corev1.PersistentVolumeClaim
+		oldPVC        *corev1.PersistentVolumeClaim
+		pods          []runtime.Object
+		dryRun        bool
+		expectErr     string
+		expectWarning bool
+		expectEvent   bool
+	}{
+		{
+			name:      "clone not supported",
+			pvc:       build.pvc("pvc", "unsupported", "1Gi", cloneSource),
+			expectErr: "does not support volume cloning",
+		},
+		{
+			name: "clone supported",
+			pvc:  build.pvc("pvc", "online", "1Gi", cloneSource),
+		},
+		{
+			name:      "restore not supported",
+			pvc:       build.pvc("pvc", "unsupported", "1Gi", snapshotSource),
+			expectErr: "does not support volume snapshot",
+		},
+		{
+			name: "restore supported",
+			pvc:  build.pvc("pvc", "offline", "1Gi", snapshotSource),
+		},
+		{
+			name: "no StorageClassCapability",
+			pvc:  build.pvc("pvc", "missing", "1Gi", cloneSource),
+		},
+		{
+			name:      "growth when expand is UNKNOWN",
+			pvc:       build.pvc("pvc", "unsupported", "2Gi", nil),
+			oldPVC:    build.pvc("pvc", "unsupported", "1Gi", nil),
+			expectErr: "does not support volume expansion",
+		},
+		{
+			name:   "no growth when expand is UNKNOWN",
+			pvc:    build.pvc("pvc", "unsupported", "1Gi", nil),
+			oldPVC: build.pvc("pvc", "unsupported", "1Gi", nil),
+		},
+		{
+			name:   "growth when expand is ONLINE",
+			pvc:    build.pvc("pvc", "online", "2Gi", nil),
+			oldPVC: build.pvc("pvc", "online", "1Gi", nil),
+			pods:   []runtime.Object{runningPod},
+		},
+		{
+			name:   "offline growth of unused claim",
+			pvc:    build.pvc("pvc", "offline", "2Gi", nil),
+			oldPVC: build.pvc("pvc", "offline", "1Gi", nil),
+		},
+		{
+			name:          "offline growth of used claim",
+			pvc:           build.pvc("pvc", "offline", "2Gi", nil),
+			oldPVC:        build.pvc("pvc", "offline", "1Gi", nil),
+			pods:          []runtime.Object{runningPod},
+			expectWarning: true,
+			expectEvent:   true,
+		},
+		{
+			name:          "offline growth of used claim on dry-run",
+			pvc:           build.pvc("pvc", "offline", "2Gi", nil),
+			oldPVC:        build.pvc("pvc", "offline", "1Gi", nil),
+			pods:          []runtime.Object{runningPod},
+			dryRun:        true,
+			expectWarning: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			validator := &PVCValidator{sccapLister: sccapInformer.Lister(), recorder: recorder}
+			var oldObj runtime.Object
+			if test.oldPVC != nil {
+				oldObj = test.oldPVC
+			}
+			req := build.request(t, pvcResource.Resource, test.pvc, oldObj)
+			if test.dryRun {
+				req.DryRun = &dryRun
+			}
+			warnings, err := validator.Validate(req, k8sfake.NewSimpleClientset(test.pods...))
+			if test.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+					t.Errorf("expected error %q, got %v", test.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected allowed, got %v", err)
+			}
+			if test.expectWarning != (len(warnings) > 0) {
+				t.Errorf("expected warning %v, got %v", test.expectWarning, warnings)
+			}
+			if events := len(recorder.Events); test.expectEvent != (events > 0) {
+				t.Errorf("expected event %v, got %d events", test.expectEvent, events)
+			}
+		})
+	}
+}
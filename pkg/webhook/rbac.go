@@ -0,0 +1,291 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"fmt"
+	pkgerrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	rbacinformers "k8s.io/client-go/informers/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"time"
+)
+
+const (
+	// LabelInjected marks the pods the sidecar has been injected into.
+	LabelInjected = "storage.kubesphere.io/storage-capability-injected"
+	// AnnotationInjectedVersion records the hash of the sidecar spec injected into the pod.
+	AnnotationInjectedVersion = "storage.kubesphere.io/storage-capability-injected-version"
+	// LabelOwner and OwnerStorageCapability mark the ClusterRoleBindings created for sidecars.
+	LabelOwner             = "owner"
+	OwnerStorageCapability = "storage-capability"
+)
+
+// RBACReconciler binds the sidecar ClusterRole to the service accounts of injected pods. It runs outside of
+// the admission path, so dry-run requests and pods denied by other webhooks leave no RBAC behind.
+// Bindings whose service account is no longer used by any injected pod are deleted, drifted bindings and
+// the sidecar ClusterRole are restored from the templates.
+type RBACReconciler struct {
+	kubeclientset kubernetes.Interface
+
+	podLister                corelisters.PodLister
+	podSynced                cache.InformerSynced
+	serviceAccountLister     corelisters.ServiceAccountLister
+	serviceAccountSynced     cache.InformerSynced
+	clusterRoleBindingLister rbaclisters.ClusterRoleBindingLister
+	clusterRoleBindingSynced cache.InformerSynced
+
+	// Keys of the workqueue are <namespace>/<service account>.
+	workqueue workqueue.RateLimitingInterface
+}
+
+// NewRBACReconciler returns a RBACReconciler. The podInformer should only list pods labeled with LabelInjected,
+// and the clusterRoleBindingInformer should only list bindings labeled with LabelOwner.
+func NewRBACReconciler(kubeclientset kubernetes.Interface, podInformer coreinformers.PodInformer,
+	serviceAccountInformer coreinformers.ServiceAccountInformer, clusterRoleBindingInformer rbacinformers.ClusterRoleBindingInformer) *RBACReconciler {
+	r := &RBACReconciler{
+		kubeclientset:            kubeclientset,
+		podLister:                podInformer.Lister(),
+		podSynced:                podInformer.Informer().HasSynced,
+		serviceAccountLister:     serviceAccountInformer.Lister(),
+		serviceAccountSynced:     serviceAccountInformer.Informer().HasSynced,
+		clusterRoleBindingLister: clusterRoleBindingInformer.Lister(),
+		clusterRoleBindingSynced: clusterRoleBindingInformer.Informer().HasSynced,
+		workqueue:                workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "SidecarRBAC"),
+	}
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.enqueuePod,
+		UpdateFunc: func(old, new interface{}) {
+			// The service account of a pod never changes, so only periodic resyncs and terminated pods
+			// are handled. Resyncs recreate bindings deleted by hand.
+			oldPod, newPod := old.(*corev1.Pod), new.(*corev1.Pod)
+			if oldPod.ResourceVersion == newPod.ResourceVersion || podTerminated(newPod) != podTerminated(oldPod) {
+				r.enqueuePod(new)
+			}
+		},
+		DeleteFunc: r.enqueuePod,
+	})
+	serviceAccountInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: r.enqueueServiceAccount,
+	})
+	// Every binding is enqueued on start, which cleans up bindings leaked before the reconciler existed.
+	clusterRoleBindingInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: r.enqueueClusterRoleBinding,
+		UpdateFunc: func(old, new interface{}) {
+			r.enqueueClusterRoleBinding(new)
+		},
+		DeleteFunc: r.enqueueClusterRoleBinding,
+	})
+	return r
+}
+
+func (r *RBACReconciler) enqueuePod(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type %T", obj))
+		return
+	}
+	r.workqueue.Add(pod.GetNamespace() + "/" + serviceAccountName(pod))
+}
+
+func (r *RBACReconciler) enqueueServiceAccount(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	r.workqueue.Add(key)
+}
+
+func (r *RBACReconciler) enqueueClusterRoleBinding(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	binding, ok := obj.(*rbacv1.ClusterRoleBinding)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("error decoding object, invalid type %T", obj))
+		return
+	}
+	if binding.GetLabels()[LabelOwner] != OwnerStorageCapability {
+		return
+	}
+	// The subjects may have drifted, the name tells the service account the binding was created for.
+	for _, subject := range binding.Subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && clusterRoleBindingName(subject.Name, subject.Namespace) == binding.GetName() {
+			r.workqueue.Add(subject.Namespace + "/" + subject.Name)
+			return
+		}
+	}
+	// Bindings of service accounts still in use are restored by the periodic resync of the pods.
+	klog.Warningf("ClusterRoleBinding %s does not bind the service account of its name", binding.GetName())
+}
+
+// serviceAccountName returns the service account of the pod, which is set by the ServiceAccount admission plugin.
+func serviceAccountName(pod *corev1.Pod) string {
+	if pod.Spec.ServiceAccountName == "" {
+		return "default"
+	}
+	return pod.Spec.ServiceAccountName
+}
+
+func podTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// Run starts workers and blocks until stopCh is closed.
+func (r *RBACReconciler) Run(threadiness int, stopCh <-chan struct{}) error {
+	defer utilruntime.HandleCrash()
+	defer r.workqueue.ShutDown()
+
+	klog.Info("Starting sidecar RBAC reconciler")
+	if ok := cache.WaitForCacheSync(stopCh, r.podSynced, r.serviceAccountSynced, r.clusterRoleBindingSynced); !ok {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	// Rules of the ClusterRole may have changed since the previous version of the webhook.
+	if err := applySidecarClusterRole(r.kubeclientset); err != nil {
+		utilruntime.HandleError(err)
+	}
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(r.runWorker, time.Second, stopCh)
+	}
+	<-stopCh
+	klog.Info("Shutting down sidecar RBAC reconciler")
+	return nil
+}
+func (r *RBACReconciler) runWorker() {
+	for r.processNextWorkItem() {
+	}
+}
+
+func (r *RBACReconciler) processNextWorkItem() bool {
+	obj, shutdown := r.workqueue.Get()
+	if shutdown {
+		return false
+	}
+	defer r.workqueue.Done(obj)
+	key, ok := obj.(string)
+	if !ok {
+		r.workqueue.Forget(obj)
+		utilruntime.HandleError(fmt.Errorf("expected string in workqueue but got %#v", obj))
+		return true
+	}
+	if err := r.syncHandler(key); err != nil {
+		r.workqueue.AddRateLimited(key)
+		utilruntime.HandleError(fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error()))
+		return true
+	}
+	r.workqueue.Forget(obj)
+	return true
+}
+
+// syncHandler binds the ClusterRole to the service account of the key if an injected pod uses it,
+// otherwise it deletes the binding.
+func (r *RBACReconciler) syncHandler(key string) error {
+	namespace, sa, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resource key: %s", key))
+		return nil
+	}
+	inUse, err := r.serviceAccountInUse(namespace, sa)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		klog.V(4).Infof("Apply ClusterRoleBinding %s", clusterRoleBindingName(sa, namespace))
+		return AddClusterRoleBinding(r.kubeclientset, sa, namespace)
+	}
+	return r.deleteClusterRoleBinding(sa, namespace)
+}
+
+// serviceAccountInUse tells whether the service account exists and a running injected pod uses it.
+func (r *RBACReconciler) serviceAccountInUse(namespace string, sa string) (bool, error) {
+	if _, err := r.serviceAccountLister.ServiceAccounts(namespace).Get(sa); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	pods, err := r.podLister.Pods(namespace).List(labels.SelectorFromSet(labels.Set{LabelInjected: "true"}))
+	if err != nil {
+		return false, err
+	}
+	for _, pod := range pods {
+		if serviceAccountName(pod) == sa && !podTerminated(pod) {
+			return true, nil
+		}
+	}
+	// Pods injected before the label was introduced are not in the cache. They are listed from the API server
+	// before deleting a binding, which happens rarely.
+	unlabeled, err := r.kubeclientset.CoreV1().Pods(namespace).List(metav1.ListOptions{
+		LabelSelector: "!" + LabelInjected,
+	})
+	if err != nil {
+		return false, pkgerrors.Wrapf(err, "list pods in namespace %s error", namespace)
+	}
+	for i := range unlabeled.Items {
+		pod := &unlabeled.Items[i]
+		if serviceAccountName(pod) == sa && !podTerminated(pod) && findContainer(pod.Spec.Containers, sidecarContainerName) >= 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deleteClusterRoleBinding deletes the binding of the service account if it is created by the webhook.
+func (r *RBACReconciler) deleteClusterRoleBinding(sa string, namespace string) error {
+	name := clusterRoleBindingName(sa, namespace)
+	binding, err := r.clusterRoleBindingLister.Get(name)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if binding.GetLabels()[LabelOwner] != OwnerStorageCapability {
+		return nil
+	}
+	klog.V(4).Infof("Delete ClusterRoleBinding %s, no injected pod uses service account %s/%s", name, namespace, sa)
+	err = r.kubeclientset.RbacV1().ClusterRoleBindings().Delete(name, &metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &binding.UID},
+	})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return pkgerrors.Wrapf(err, "delete ClusterRoleBinding %s error", name)
+	}
+	return nil
+}
+
+// clusterRoleBindingName returns the name of the binding created for the service account.
+func clusterRoleBindingName(sa string, namespace string) string {
+	return sa + "-in-" + namespace
+}
@@ -0,0 +1,155 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+// newTestRBACReconciler returns a RBACReconciler whose clientset and caches hold the objects.
+func newTestRBACReconciler(objects ...runtime.Object) (*RBACReconciler, *k8sfake.Clientset) {
+	client := k8sfake.NewSimpleClientset(objects...)
+	factory := kubeinformers.NewSharedInformerFactory(client, 0)
+	podInformer := factory.Core().V1().Pods()
+	serviceAccountInformer := factory.Core().V1().ServiceAccounts()
+	clusterRoleBindingInformer := factory.Rbac().V1().ClusterRoleBindings()
+	for _, obj := range objects {
+		switch obj.(type) {
+		case *corev1.Pod:
+			// The pod informer of the webhook only lists injected pods.
+			if obj.(*corev1.Pod).Labels[LabelInjected] == "true" {
+				podInformer.Informer().GetIndexer().Add(obj)
+			}
+		case *corev1.ServiceAccount:
+			serviceAccountInformer.Informer().GetIndexer().Add(obj)
+		case *rbacv1.ClusterRoleBinding:
+			clusterRoleBindingInformer.Informer().GetIndexer().Add(obj)
+		}
+	}
+	return NewRBACReconciler(client, podInformer, serviceAccountInformer, clusterRoleBindingInformer), client
+}
+
+// unlabeledPod removes the injected label, like on pods injected before the label was introduced.
+func unlabeledPod(pod *corev1.Pod) *corev1.Pod {
+	pod.Labels = nil
+	return pod
+}
+
+func TestRBACReconciler(t *testing.T) {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "csi", Namespace: "default"}}
+	owned := map[string]string{LabelOwner: OwnerStorageCapability}
+	tests := []struct {
+		name          string
+		objects       []runtime.Object
+		expectBinding bool
+		expectUID     types.UID
+	}{
+		{
+			name:          "create on injected pod",
+			objects:       []runtime.Object{sa, build.injectedPod("csi-0", "csi", corev1.PodRunning)},
+			expectBinding: true,
+		},
+		{
+			name: "keep while another pod runs",
+			objects: []runtime.Object{sa,
+				build.injectedPod("csi-0", "csi", corev1.PodSucceeded),
+				build.injectedPod("csi-1", "csi", corev1.PodRunning),
+				build.clusterRoleBinding("csi", clusterRoleName, owned),
+			},
+			expectBinding: true,
+			expectUID:     "old",
+		},
+		{
+			name: "delete on last pod",
+			objects: []runtime.Object{sa,
+				build.injectedPod("csi-0", "csi", corev1.PodFailed),
+				build.clusterRoleBinding("csi", clusterRoleName, owned),
+			},
+		},
+		{
+			name: "keep binding of unlabeled injected pod",
+			objects: []runtime.Object{sa,
+				unlabeledPod(build.injectedPod("csi-0", "csi", corev1.PodRunning)),
+				build.clusterRoleBinding("csi", clusterRoleName, owned),
+			},
+			expectBinding: true,
+			expectUID:     "old",
+		},
+		{
+			name: "delete with unlabeled pod without sidecar",
+			objects: []runtime.Object{sa,
+				build.pod("app", "csi", ""),
+				build.clusterRoleBinding("csi", clusterRoleName, owned),
+			},
+		},
+		{
+			name: "delete on service account deletion",
+			objects: []runtime.Object{
+				build.injectedPod("csi-0", "csi", corev1.PodRunning),
+				build.clusterRoleBinding("csi", clusterRoleName, owned),
+			},
+		},
+		{
+			name:          "keep binding not created by the webhook",
+			objects:       []runtime.Object{sa, build.clusterRoleBinding("csi", clusterRoleName, nil)},
+			expectBinding: true,
+			expectUID:     "old",
+		},
+		{
+			name: "recreate on roleRef drift",
+			objects: []runtime.Object{sa,
+				build.injectedPod("csi-0", "csi", corev1.PodRunning),
+				build.clusterRoleBinding("csi", "cluster-admin", owned),
+			},
+			expectBinding: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reconciler, client := newTestRBACReconciler(test.objects...)
+			if err := reconciler.syncHandler("default/csi"); err != nil {
+				t.Fatalf("sync error: %v", err)
+			}
+			binding, err := client.RbacV1().ClusterRoleBindings().Get(clusterRoleBindingName("csi", "default"), metav1.GetOptions{})
+			if !test.expectBinding {
+				if !k8serrors.IsNotFound(err) {
+					t.Errorf("expected binding to be deleted, got %v %v", binding, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected binding, got %v", err)
+			}
+			if binding.RoleRef.Name != clusterRoleName {
+				t.Errorf("expected roleRef %s, got %s", clusterRoleName, binding.RoleRef.Name)
+			}
+			if binding.UID != test.expectUID {
+				t.Errorf("expected UID %q, got %q", test.expectUID, binding.UID)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"k8s.io/api/admission/v1"
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reviews are handled as admission/v1 and converted from and to the version sent by the API server,
+// which is the first of the admissionReviewVersions of the webhook configuration it supports.
+
+// decodeAdmissionReview returns the request of an AdmissionReview of any supported version, and the version.
+func decodeAdmissionReview(body []byte) (*v1.AdmissionRequest, string, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(body, &typeMeta); err != nil {
+		return nil, "", err
+	}
+	if typeMeta.Kind != "AdmissionReview" {
+		return nil, "", fmt.Errorf("unsupported kind %q, only AdmissionReview is supported", typeMeta.Kind)
+	}
+	switch typeMeta.APIVersion {
+	case v1.SchemeGroupVersion.String():
+		review := v1.AdmissionReview{}
+		if _, _, err := universalDeserializer.Decode(body, nil, &review); err != nil {
+			return nil, "", err
+		}
+		return review.Request, typeMeta.APIVersion, nil
+	case v1beta1.SchemeGroupVersion.String():
+		review := v1beta1.AdmissionReview{}
+		if _, _, err := universalDeserializer.Decode(body, nil, &review); err != nil {
+			return nil, "", err
+		}
+		return convertRequestFromV1beta1(review.Request), typeMeta.APIVersion, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported AdmissionReview version %q, only %s and %s are supported",
+			typeMeta.APIVersion, v1.SchemeGroupVersion, v1beta1.SchemeGroupVersion)
+	}
+}
+
+// encodeAdmissionReview returns the AdmissionReview of the response in the given version.
+func encodeAdmissionReview(apiVersion string, response *v1.AdmissionResponse) ([]byte, error) {
+	typeMeta := metav1.TypeMeta{APIVersion: apiVersion, Kind: "AdmissionReview"}
+	switch apiVersion {
+	case v1.SchemeGroupVersion.String():
+		return json.Marshal(&v1.AdmissionReview{TypeMeta: typeMeta, Response: response})
+	case v1beta1.SchemeGroupVersion.String():
+		return json.Marshal(&v1beta1.AdmissionReview{TypeMeta: typeMeta, Response: convertResponseToV1beta1(response)})
+	default:
+		return nil, fmt.Errorf("unsupported AdmissionReview version %q", apiVersion)
+	}
+}
+
+func convertRequestFromV1beta1(in *v1beta1.AdmissionRequest) *v1.AdmissionRequest {
+	if in == nil {
+		return nil
+	}
+	return &v1.AdmissionRequest{
+		UID:                in.UID,
+		Kind:               in.Kind,
+		Resource:           in.Resource,
+		SubResource:        in.SubResource,
+		RequestKind:        in.RequestKind,
+		RequestResource:    in.RequestResource,
+		RequestSubResource: in.RequestSubResource,
+		Name:               in.Name,
+		Namespace:          in.Namespace,
+		Operation:          v1.Operation(in.Operation),
+		UserInfo:           in.UserInfo,
+		Object:             in.Object,
+		OldObject:          in.OldObject,
+		DryRun:             in.DryRun,
+		Options:            in.Options,
+	}
+}
+
+func convertResponseToV1beta1(in *v1.AdmissionResponse) *v1beta1.AdmissionResponse {
+	out := &v1beta1.AdmissionResponse{
+		UID:              in.UID,
+		Allowed:          in.Allowed,
+		Result:           in.Result,
+		Patch:            in.Patch,
+		AuditAnnotations: in.AuditAnnotations,
+	}
+	if in.PatchType != nil {
+		patchType := v1beta1.PatchType(*in.PatchType)
+		out.PatchType = &patchType
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/api/admission/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog"
+	"net/http"
+)
+
+var (
+	validExpandModes = []string{
+		string(v1alpha1.ExpandModeUnknown),
+		string(v1alpha1.ExpandModeOffline),
+		string(v1alpha1.ExpandModeOnline),
+	}
+	expandModePath = field.NewPath("spec", "features", "volume", "expandMode")
+)
+
+// validateFunc returns the warnings of an allowed request, or an error to deny it.
+type validateFunc func(*v1.AdmissionRequest, kubernetes.Interface) ([]string, error)
+
+// ValidateFuncHandler serves a validating webhook, which answers without patches.
+func ValidateFuncHandler(validate validateFunc, k8sClient kubernetes.Interface) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveAdmitFunc(w, r, func(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]patchOperation, []string, error) {
+			warnings, err := validate(req, k8sClient)
+			return nil, warnings, err
+		}, k8sClient)
+	})
+}
+
+// CapabilityValidator validates ProvisionerCapability and StorageClassCapability of any served version.
+type CapabilityValidator struct {
+	scLister storagelisters.StorageClassLister
+}
+
+// NewCapabilityValidator returns a CapabilityValidator reading StorageClasses from the cache of the lister.
+func NewCapabilityValidator(scLister storagelisters.StorageClassLister) *CapabilityValidator {
+	return &CapabilityValidator{scLister: scLister}
+}
+
+// Validate denies capability objects with invalid fields.
+func (c *CapabilityValidator) Validate(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]string, error) {
+	return nil, validateCapability(req, c.scLister)
+}
+
+func validateCapability(req *v1.AdmissionRequest, scLister storagelisters.StorageClassLister) error {
+	if req.Operation != v1.Create && req.Operation != v1.Update {
+		return nil
+	}
+	if req.Resource.Group != v1alpha1.SchemeGroupVersion.Group {
+		return nil
+	}
+	// Objects are validated in v1alpha1, so the rules are only written once.
+	raw, err := convertObject(req.Object.Raw, v1alpha1.SchemeGroupVersion)
+	if err != nil {
+		return pkgerrors.Wrap(err, "could not convert object")
+	}
+	var errs field.ErrorList
+	switch req.Resource.Resource {
+	case "provisionercapabilities":
+		pcap := &v1alpha1.ProvisionerCapability{}
+		if err := json.Unmarshal(raw, pcap); err != nil {
+			return fmt.Errorf("could not deserialize ProvisionerCapability: %v", err)
+		}
+		errs = validateProvisionerCapability(pcap)
+	case "storageclasscapabilities":
+		sccap := &v1alpha1.StorageClassCapability{}
+		if err := json.Unmarshal(raw, sccap); err != nil {
+			return fmt.Errorf("could not deserialize StorageClassCapability: %v", err)
+		}
+		var oldSccap *v1alpha1.StorageClassCapability
+		if req.Operation == v1.Update {
+			oldRaw, err := convertObject(req.OldObject.Raw, v1alpha1.SchemeGroupVersion)
+			if err != nil {
+				return pkgerrors.Wrap(err, "could not convert old object")
+			}
+			oldSccap = &v1alpha1.StorageClassCapability{}
+			if err := json.Unmarshal(oldRaw, oldSccap); err != nil {
+				return fmt.Errorf("could not deserialize old StorageClassCapability: %v", err)
+			}
+		}
+		errs = validateStorageClassCapability(sccap, oldSccap, scLister)
+	default:
+		return nil
+	}
+	return errs.ToAggregate()
+}
+
+func validateProvisionerCapability(pcap *v1alpha1.ProvisionerCapability) field.ErrorList {
+	var errs field.ErrorList
+	if pcap.GetName() != pcap.Spec.PluginInfo.Name {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "pluginInfo", "name"), pcap.Spec.PluginInfo.Name,
+			fmt.Sprintf("must be the same as the name %s", pcap.GetName())))
+	}
+	return append(errs, validateExpandMode(pcap.Spec.Features.Volume.Expand)...)
+}
+
+// validateStorageClassCapability checks the provisioner against the StorageClass of the same name on creation,
+// and on updates changing it. The oldSccap is nil on creation. The provisioner is not checked if the StorageClass
+// is not found or can not be read: the cache may lag behind the controller, and the garbage collector updates
+// StorageClassCapabilities orphaned by their StorageClass.
+func validateStorageClassCapability(sccap *v1alpha1.StorageClassCapability, oldSccap *v1alpha1.StorageClassCapability,
+	scLister storagelisters.StorageClassLister) field.ErrorList {
+	errs := validateExpandMode(sccap.Spec.Features.Volume.Expand)
+	if oldSccap != nil && oldSccap.Spec.Provisioner == sccap.Spec.Provisioner {
+		return errs
+	}
+	provisionerPath := field.NewPath("spec", "provisioner")
+	sc, err := scLister.Get(sccap.GetName())
+	if k8serrors.IsNotFound(err) {
+		klog.V(4).Infof("Skip validating provisioner of StorageClassCapability %s, StorageClass not found", sccap.GetName())
+		return errs
+	}
+	if err != nil {
+		klog.Errorf("Skip validating provisioner of StorageClassCapability %s, get StorageClass error: %s", sccap.GetName(), err)
+		return errs
+	}
+	if sc.Provisioner != sccap.Spec.Provisioner {
+		errs = append(errs, field.Invalid(provisionerPath, sccap.Spec.Provisioner,
+			fmt.Sprintf("must be the same as the provisioner %s of StorageClass %s", sc.Provisioner, sc.GetName())))
+	}
+	return errs
+}
+
+func validateExpandMode(mode v1alpha1.ExpandMode) field.ErrorList {
+	for _, valid := range validExpandModes {
+		if string(mode) == valid {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(expandModePath, mode, validExpandModes)}
+}
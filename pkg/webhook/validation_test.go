@@ -0,0 +1,161 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"errors"
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	v1beta1cap "github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1beta1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"strings"
+	"testing"
+)
+
+// failingStorageClassLister fails every lookup, like a cache which is not available.
+type failingStorageClassLister struct {
+	storagelisters.StorageClassLister
+}
+
+func (l failingStorageClassLister) Get(name string) (*storagev1.StorageClass, error) {
+	return nil, errors.New("cache not available")
+}
+
+func TestValidateCapability(t *testing.T) {
+	scInformer := kubeinformers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0).Storage().V1().StorageClasses()
+	scInformer.Informer().GetIndexer().Add(build.storageClass("sc-example"))
+	orphaned := build.sccap("sc-deleted", v1alpha1.ExpandModeOffline)
+	orphaned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "storage.k8s.io/v1", Kind: "StorageClass", Name: "sc-deleted", UID: "sc-uid"}}
+	tests := []struct {
+		name      string
+		resource  string
+		obj       runtime.Object
+		oldObj    runtime.Object
+		scLister  storagelisters.StorageClassLister
+		expectErr string
+	}{
+		{
+			name:     "valid pcap",
+			resource: "provisionercapabilities",
+			obj:      build.pcap("csi.example.com", v1alpha1.ExpandModeOnline),
+		},
+		{
+			name:      "pcap name differs from plugin name",
+			resource:  "provisionercapabilities",
+			obj:       build.pcap("csi.other.com", v1alpha1.ExpandModeOnline),
+			expectErr: "spec.pluginInfo.name",
+		},
+		{
+			name:     "pcap of v1beta1 converted",
+			resource: "provisionercapabilities",
+			obj: &v1beta1cap.ProvisionerCapability{
+				TypeMeta:   metav1.TypeMeta{APIVersion: v1beta1cap.SchemeGroupVersion.String(), Kind: "ProvisionerCapability"},
+				ObjectMeta: metav1.ObjectMeta{Name: "csi.example.com"},
+				Spec: v1beta1cap.ProvisionerCapabilitySpec{
+					PluginInfo: v1beta1cap.PluginInfo{Name: "csi.other.com"},
+					Features: v1beta1cap.Features{
+						Volume: v1beta1cap.VolumeFeatures{ExpandMode: v1beta1cap.ExpandMode(v1alpha1.ExpandModeOnline)},
+					},
+				},
+			},
+			expectErr: "spec.pluginInfo.name",
+		},
+		{
+			name:      "pcap expand mode empty",
+			resource:  "provisionercapabilities",
+			obj:       build.pcap("csi.example.com", ""),
+			expectErr: "spec.features.volume.expandMode",
+		},
+		{
+			name:      "pcap expand mode unsupported",
+			resource:  "provisionercapabilities",
+			obj:       build.pcap("csi.example.com", "online"),
+			expectErr: "spec.features.volume.expandMode",
+		},
+		{
+			name:     "valid sccap",
+			resource: "storageclasscapabilities",
+			obj:      build.sccap("sc-example", v1alpha1.ExpandModeOffline),
+		},
+		{
+			name:      "sccap provisioner differs from StorageClass",
+			resource:  "storageclasscapabilities",
+			obj:       build.sccap("sc-example", v1alpha1.ExpandModeOffline, withProvisioner("csi.other.com")),
+			expectErr: "spec.provisioner",
+		},
+		{
+			name:     "sccap StorageClass not found",
+			resource: "storageclasscapabilities",
+			obj:      build.sccap("sc-missing", v1alpha1.ExpandModeOffline),
+		},
+		{
+			name:     "sccap orphaned by StorageClass updated by garbage collector",
+			resource: "storageclasscapabilities",
+			obj:      build.sccap("sc-deleted", v1alpha1.ExpandModeOffline),
+			oldObj:   orphaned,
+		},
+		{
+			name:     "sccap update keeping a provisioner different from StorageClass",
+			resource: "storageclasscapabilities",
+			obj:      build.sccap("sc-example", v1alpha1.ExpandModeOnline, withProvisioner("csi.other.com")),
+			oldObj:   build.sccap("sc-example", v1alpha1.ExpandModeOffline, withProvisioner("csi.other.com")),
+		},
+		{
+			name:      "sccap update changing provisioner",
+			resource:  "storageclasscapabilities",
+			obj:       build.sccap("sc-example", v1alpha1.ExpandModeOffline, withProvisioner("csi.other.com")),
+			oldObj:    build.sccap("sc-example", v1alpha1.ExpandModeOffline),
+			expectErr: "spec.provisioner",
+		},
+		{
+			name:     "sccap StorageClass lookup failed",
+			resource: "storageclasscapabilities",
+			obj:      build.sccap("sc-example", v1alpha1.ExpandModeOffline, withProvisioner("csi.other.com")),
+			scLister: failingStorageClassLister{},
+		},
+		{
+			name:      "sccap expand mode unsupported",
+			resource:  "storageclasscapabilities",
+			obj:       build.sccap("sc-example", "OFF"),
+			expectErr: "spec.features.volume.expandMode",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scLister := test.scLister
+			if scLister == nil {
+				scLister = scInformer.Lister()
+			}
+			_, err := NewCapabilityValidator(scLister).Validate(build.request(t, test.resource, test.obj, test.oldObj), nil)
+			if test.expectErr == "" {
+				if err != nil {
+					t.Errorf("expected allowed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("expected error of %s, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"fmt"
+	crdlisters "github.com/kubesphere/storage-capability/pkg/generated/listers/storagecapability/v1alpha1"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/api/admission/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SnapshotValidator denies VolumeSnapshots of claims whose StorageClass does not support snapshot, or
+// whose VolumeSnapshotClass belongs to another driver.
+type SnapshotValidator struct {
+	sccapLister   crdlisters.StorageClassCapabilityLister
+	dynamicClient dynamic.Interface
+}
+
+// NewSnapshotValidator returns a SnapshotValidator reading VolumeSnapshotClasses with the dynamic client.
+func NewSnapshotValidator(sccapLister crdlisters.StorageClassCapabilityLister, dynamicClient dynamic.Interface) *SnapshotValidator {
+	return &SnapshotValidator{sccapLister: sccapLister, dynamicClient: dynamicClient}
+}
+
+// Validate checks VolumeSnapshots of any supported version on creation.
+func (s *SnapshotValidator) Validate(req *v1.AdmissionRequest, k8sClient kubernetes.Interface) ([]string, error) {
+	if req.Operation != v1.Create || req.Resource.Group != snapshot.GroupName || req.Resource.Resource != "volumesnapshots" {
+		return nil, nil
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+		return nil, fmt.Errorf("could not deserialize VolumeSnapshot: %v", err)
+	}
+	// A VolumeSnapshot of a pre-provisioned VolumeSnapshotContent has no source claim.
+	claimName, _, err := unstructured.NestedString(obj.Object, "spec", "source", "persistentVolumeClaimName")
+	if err != nil || claimName == "" {
+		return nil, err
+	}
+	claimPath := field.NewPath("spec", "source", "persistentVolumeClaimName")
+	pvc, err := k8sClient.CoreV1().PersistentVolumeClaims(req.Namespace).Get(claimName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, field.NotFound(claimPath, claimName)
+	}
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "get PersistentVolumeClaim %s/%s error", req.Namespace, claimName)
+	}
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return nil, nil
+	}
+	sccap, err := s.sccapLister.Get(*pvc.Spec.StorageClassName)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !sccap.Spec.Features.Snapshot.Create {
+		return nil, field.Forbidden(claimPath, fmt.Sprintf("StorageClass %s of PersistentVolumeClaim %s does not support volume snapshot", sccap.GetName(), claimName))
+	}
+
+	// The snapshot controller chooses the default class of the driver if no class is given.
+	className, _, err := unstructured.NestedString(obj.Object, "spec", "volumeSnapshotClassName")
+	if err != nil || className == "" {
+		return nil, err
+	}
+	classPath := field.NewPath("spec", "volumeSnapshotClassName")
+	u, err := s.dynamicClient.Resource(snapshot.ClassResource(req.Resource.Version)).Get(className, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, field.NotFound(classPath, className)
+	}
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "get VolumeSnapshotClass %s error", className)
+	}
+	class, err := snapshot.ClassFromUnstructured(u)
+	if err != nil {
+		return nil, err
+	}
+	if class.Driver != sccap.Spec.Provisioner {
+		return nil, field.Invalid(classPath, className, fmt.Sprintf("driver %s of VolumeSnapshotClass does not match provisioner %s of StorageClass %s",
+			class.Driver, sccap.Spec.Provisioner, sccap.GetName()))
+	}
+	return nil, nil
+}
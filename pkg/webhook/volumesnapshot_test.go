@@ -0,0 +1,117 @@
+/*
+
+ Copyright 2020 The KubeSphere Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+
+*/
+
+package webhook
+
+import (
+	"github.com/kubesphere/storage-capability/pkg/apis/storagecapability/v1alpha1"
+	crdfake "github.com/kubesphere/storage-capability/pkg/generated/clientset/versioned/fake"
+	crdinformers "github.com/kubesphere/storage-capability/pkg/generated/informers/externalversions"
+	"github.com/kubesphere/storage-capability/pkg/snapshot"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotValidator(t *testing.T) {
+	sccapInformer := crdinformers.NewSharedInformerFactory(crdfake.NewSimpleClientset(), 0).Storage().V1alpha1().StorageClassCapabilities()
+	for _, sccap := range []*v1alpha1.StorageClassCapability{
+		build.sccap("unsupported", v1alpha1.ExpandModeUnknown),
+		build.sccap("supported", v1alpha1.ExpandModeOnline, withClone, withSnapshot),
+	} {
+		sccapInformer.Informer().GetIndexer().Add(sccap)
+	}
+	var classes []runtime.Object
+	for _, class := range []*snapshot.VolumeSnapshotClass{
+		{ObjectMeta: metav1.ObjectMeta{Name: "snap-example"}, Driver: "csi.example.com", DeletionPolicy: "Delete"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "snap-other"}, Driver: "csi.other.com", DeletionPolicy: "Delete"},
+	} {
+		u, err := class.ToUnstructured(snapshot.V1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes = append(classes, u)
+	}
+	claims := []runtime.Object{
+		build.pvc("pvc-unsupported", "unsupported", "1Gi", nil),
+		build.pvc("pvc-supported", "supported", "1Gi", nil),
+		build.pvc("pvc-no-sccap", "missing", "1Gi", nil),
+	}
+	validator := NewSnapshotValidator(sccapInformer.Lister(), dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), classes...))
+	tests := []struct {
+		name      string
+		claimName string
+		className string
+		expectErr string
+	}{
+		{
+			name:      "missing claim",
+			claimName: "pvc-missing",
+			expectErr: "spec.source.persistentVolumeClaimName: Not found",
+		},
+		{
+			name:      "missing StorageClassCapability",
+			claimName: "pvc-no-sccap",
+			className: "snap-other",
+		},
+		{
+			name:      "snapshot not supported",
+			claimName: "pvc-unsupported",
+			expectErr: "does not support volume snapshot",
+		},
+		{
+			name:      "class driver mismatch",
+			claimName: "pvc-supported",
+			className: "snap-other",
+			expectErr: "does not match provisioner csi.example.com",
+		},
+		{
+			name:      "missing class",
+			claimName: "pvc-supported",
+			className: "snap-missing",
+			expectErr: "spec.volumeSnapshotClassName: Not found",
+		},
+		{
+			name:      "matching class",
+			claimName: "pvc-supported",
+			className: "snap-example",
+		},
+		{
+			name:      "default class",
+			claimName: "pvc-supported",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := build.request(t, "volumesnapshots", build.volumeSnapshot(test.claimName, test.className), nil)
+			_, err := validator.Validate(req, k8sfake.NewSimpleClientset(claims...))
+			if test.expectErr == "" {
+				if err != nil {
+					t.Errorf("expected allowed, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.expectErr) {
+				t.Errorf("expected error %q, got %v", test.expectErr, err)
+			}
+		})
+	}
+}